@@ -0,0 +1,413 @@
+package attribute
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"math/big"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// CoerceValue Convert a dynamically typed value, such as one read from
+// YAML, JSON, a Terraform cty.Value or an HTTP API, to the type of the
+// attribute. An error is returned if the conversion would lose data.
+//
+// Supported conversions:
+//   - cty.Value to the equivalent Go value
+//   - json.Number, as decoded with json.Decoder.UseNumber, to int64,
+//     or to float64 if it is not an integer
+//   - string to int, uint, float, bool and time.Duration
+//   - numbers and bools to string
+//   - float64 to int, if the value is a whole number
+//   - numbers to time.Duration, as seconds (e.g. 3600 is one hour)
+//   - lists ([]any) to typed slices and maps to typed maps
+//   - fields of object attributes to the types of the fields
+func (a *Attribute) CoerceValue(value any) (any, error) {
+	coerced, err := coerceValue(value, a.Type)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot coerce value for attribute %s: %s", a.Name, err)
+	}
+	// The coerced object may be the caller's map, which must not be modified
+	if object, ok := coerced.(map[string]any); ok && object != nil && a.Type == ObjectType && len(a.Fields) > 0 {
+		result := make(map[string]any, len(object))
+		for key, fieldValue := range object {
+			result[key] = fieldValue
+			field := a.GetField(AttributeName(key))
+			if field == nil {
+				continue
+			}
+			coercedField, err := field.CoerceValue(fieldValue)
+			if err != nil {
+				return nil, fmt.Errorf("Cannot coerce value for attribute %s: %s", a.Name, err)
+			}
+			result[key] = coercedField
+		}
+		return result, nil
+	}
+	return coerced, nil
+}
+
+// ctyToNative Convert a cty.Value to the equivalent Go value
+func ctyToNative(value cty.Value) (any, error) {
+	if value.IsNull() {
+		return nil, nil
+	}
+	if !value.IsKnown() {
+		return nil, fmt.Errorf("cty value is unknown")
+	}
+	valueType := value.Type()
+	switch {
+	case valueType == cty.String:
+		return value.AsString(), nil
+	case valueType == cty.Bool:
+		return value.True(), nil
+	case valueType == cty.Number:
+		bigFloat := value.AsBigFloat()
+		if bigFloat.IsInt() {
+			if i, accuracy := bigFloat.Int64(); accuracy == big.Exact {
+				return i, nil
+			}
+		}
+		f, accuracy := bigFloat.Float64()
+		if accuracy != big.Exact {
+			return nil, fmt.Errorf("cty number %s cannot be represented as float64", bigFloat.String())
+		}
+		return f, nil
+	case valueType.IsListType() || valueType.IsSetType() || valueType.IsTupleType():
+		list := []any{}
+		for it := value.ElementIterator(); it.Next(); {
+			_, element := it.Element()
+			native, err := ctyToNative(element)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, native)
+		}
+		return list, nil
+	case valueType.IsMapType() || valueType.IsObjectType():
+		object := map[string]any{}
+		for it := value.ElementIterator(); it.Next(); {
+			key, element := it.Element()
+			native, err := ctyToNative(element)
+			if err != nil {
+				return nil, err
+			}
+			object[key.AsString()] = native
+		}
+		return object, nil
+	}
+	return nil, fmt.Errorf("Unsupported cty type: %s", valueType.FriendlyName())
+}
+
+// jsonNumbersToNative Convert json.Numbers, including those in lists
+// and maps, to int64, uint64 or float64. Lists and maps containing
+// numbers are copied, rather than modified
+func jsonNumbersToNative(value any) (any, bool, error) {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, true, nil
+		}
+		if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return u, true, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, false, fmt.Errorf("Invalid JSON number %s", v)
+		}
+		return f, true, nil
+	case []any:
+		var converted []any
+		for i, element := range v {
+			native, changed, err := jsonNumbersToNative(element)
+			if err != nil {
+				return nil, false, err
+			}
+			if changed && converted == nil {
+				converted = slices.Clone(v)
+			}
+			if converted != nil {
+				converted[i] = native
+			}
+		}
+		if converted != nil {
+			return converted, true, nil
+		}
+	case map[string]any:
+		var converted map[string]any
+		for key, element := range v {
+			native, changed, err := jsonNumbersToNative(element)
+			if err != nil {
+				return nil, false, err
+			}
+			if changed && converted == nil {
+				converted = maps.Clone(v)
+			}
+			if converted != nil {
+				converted[key] = native
+			}
+		}
+		if converted != nil {
+			return converted, true, nil
+		}
+	}
+	return value, false, nil
+}
+
+func coerceValue(value any, targetType reflect.Type) (any, error) {
+	if ctyValue, ok := value.(cty.Value); ok {
+		native, err := ctyToNative(ctyValue)
+		if err != nil {
+			return nil, err
+		}
+		value = native
+	}
+	value, _, err := jsonNumbersToNative(value)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return reflect.Zero(targetType).Interface(), nil
+	}
+	if reflect.TypeOf(value) == targetType {
+		return value, nil
+	}
+	if targetType.Kind() == reflect.Interface && reflect.TypeOf(value).Implements(targetType) {
+		return value, nil
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	var coerced reflect.Value
+	switch {
+	case targetType == DurationType:
+		coerced, err = coerceDuration(reflectValue)
+	case targetType.Kind() == reflect.String:
+		coerced, err = coerceString(reflectValue)
+	case targetType.Kind() == reflect.Bool:
+		coerced, err = coerceBool(reflectValue)
+	case reflectValue.CanConvert(targetType) && isNumberKind(targetType.Kind()):
+		coerced, err = coerceNumber(reflectValue, targetType)
+	case isNumberKind(targetType.Kind()) && reflectValue.Kind() == reflect.String:
+		coerced, err = coerceNumberFromString(reflectValue.String(), targetType)
+	case targetType.Kind() == reflect.Slice:
+		coerced, err = coerceSlice(reflectValue, targetType)
+	case targetType.Kind() == reflect.Map:
+		coerced, err = coerceMap(reflectValue, targetType)
+	default:
+		err = fmt.Errorf("Unsupported conversion")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Cannot coerce '%v' (%T) to %s: %s", value, value, targetType, err)
+	}
+	return coerced.Convert(targetType).Interface(), nil
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func coerceDuration(value reflect.Value) (reflect.Value, error) {
+	if value.Kind() == reflect.String {
+		duration, err := time.ParseDuration(value.String())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(duration), nil
+	}
+	// Numbers are treated as seconds, which is how durations are
+	// commonly written in JSON and YAML, rather than nanoseconds
+	seconds, err := coerceNumber(value, reflect.TypeOf(float64(0)))
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	nanoseconds := math.Round(seconds.Float() * float64(time.Second))
+	if nanoseconds < math.MinInt64 || nanoseconds >= math.MaxInt64 {
+		return reflect.Value{}, fmt.Errorf("Value overflows %s", DurationType)
+	}
+	return reflect.ValueOf(time.Duration(nanoseconds)), nil
+}
+
+func coerceString(value reflect.Value) (reflect.Value, error) {
+	switch {
+	case value.Kind() == reflect.String:
+		return reflect.ValueOf(value.String()), nil
+	case value.Kind() == reflect.Bool:
+		return reflect.ValueOf(strconv.FormatBool(value.Bool())), nil
+	case value.CanInt():
+		return reflect.ValueOf(strconv.FormatInt(value.Int(), 10)), nil
+	case value.CanUint():
+		return reflect.ValueOf(strconv.FormatUint(value.Uint(), 10)), nil
+	case value.CanFloat():
+		return reflect.ValueOf(strconv.FormatFloat(value.Float(), 'f', -1, 64)), nil
+	}
+	return reflect.Value{}, fmt.Errorf("Unsupported conversion")
+}
+
+func coerceBool(value reflect.Value) (reflect.Value, error) {
+	if value.Kind() != reflect.String {
+		return reflect.Value{}, fmt.Errorf("Unsupported conversion")
+	}
+	b, err := strconv.ParseBool(value.String())
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("Not a boolean")
+	}
+	return reflect.ValueOf(b), nil
+}
+
+func coerceNumberFromString(s string, targetType reflect.Type) (reflect.Value, error) {
+	switch {
+	case targetType.Kind() == reflect.Float32 || targetType.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("Not a number")
+		}
+		return coerceNumber(reflect.ValueOf(f), targetType)
+	case reflect.Zero(targetType).CanUint():
+		u, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("Not an unsigned integer")
+		}
+		return coerceNumber(reflect.ValueOf(u), targetType)
+	default:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("Not an integer")
+		}
+		return coerceNumber(reflect.ValueOf(i), targetType)
+	}
+}
+
+// coerceNumber Convert between numeric types, returning an error
+// if the value would be truncated or overflow
+func coerceNumber(value reflect.Value, targetType reflect.Type) (reflect.Value, error) {
+	target := reflect.New(targetType).Elem()
+	switch {
+	case target.CanInt():
+		var i int64
+		switch {
+		case value.CanInt():
+			i = value.Int()
+		case value.CanUint():
+			if value.Uint() > math.MaxInt64 {
+				return reflect.Value{}, fmt.Errorf("Value overflows %s", targetType)
+			}
+			i = int64(value.Uint())
+		case value.CanFloat():
+			f := value.Float()
+			if f != math.Trunc(f) {
+				return reflect.Value{}, fmt.Errorf("Value is not a whole number")
+			}
+			if f < math.MinInt64 || f >= math.MaxInt64 {
+				return reflect.Value{}, fmt.Errorf("Value overflows %s", targetType)
+			}
+			i = int64(f)
+		default:
+			return reflect.Value{}, fmt.Errorf("Unsupported conversion")
+		}
+		if target.OverflowInt(i) {
+			return reflect.Value{}, fmt.Errorf("Value overflows %s", targetType)
+		}
+		target.SetInt(i)
+	case target.CanUint():
+		var u uint64
+		switch {
+		case value.CanInt():
+			if value.Int() < 0 {
+				return reflect.Value{}, fmt.Errorf("Value is negative")
+			}
+			u = uint64(value.Int())
+		case value.CanUint():
+			u = value.Uint()
+		case value.CanFloat():
+			f := value.Float()
+			if f != math.Trunc(f) {
+				return reflect.Value{}, fmt.Errorf("Value is not a whole number")
+			}
+			if f < 0 || f >= math.MaxUint64 {
+				return reflect.Value{}, fmt.Errorf("Value overflows %s", targetType)
+			}
+			u = uint64(f)
+		default:
+			return reflect.Value{}, fmt.Errorf("Unsupported conversion")
+		}
+		if target.OverflowUint(u) {
+			return reflect.Value{}, fmt.Errorf("Value overflows %s", targetType)
+		}
+		target.SetUint(u)
+	case target.CanFloat():
+		var f float64
+		switch {
+		case value.CanInt():
+			f = float64(value.Int())
+		case value.CanUint():
+			f = float64(value.Uint())
+		case value.CanFloat():
+			f = value.Float()
+		default:
+			return reflect.Value{}, fmt.Errorf("Unsupported conversion")
+		}
+		if target.OverflowFloat(f) {
+			return reflect.Value{}, fmt.Errorf("Value overflows %s", targetType)
+		}
+		target.SetFloat(f)
+	default:
+		return reflect.Value{}, fmt.Errorf("Unsupported conversion")
+	}
+	return target, nil
+}
+
+func coerceSlice(value reflect.Value, targetType reflect.Type) (reflect.Value, error) {
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return reflect.Value{}, fmt.Errorf("Value is not a list")
+	}
+	result := reflect.MakeSlice(targetType, 0, value.Len())
+	for i := 0; i < value.Len(); i++ {
+		element, err := coerceValue(value.Index(i).Interface(), targetType.Elem())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("Element %d: %s", i, err)
+		}
+		elementValue := reflect.ValueOf(element)
+		if element == nil {
+			elementValue = reflect.Zero(targetType.Elem())
+		}
+		result = reflect.Append(result, elementValue)
+	}
+	return result, nil
+}
+
+func coerceMap(value reflect.Value, targetType reflect.Type) (reflect.Value, error) {
+	if value.Kind() != reflect.Map {
+		return reflect.Value{}, fmt.Errorf("Value is not a map")
+	}
+	result := reflect.MakeMapWithSize(targetType, value.Len())
+	iter := value.MapRange()
+	for iter.Next() {
+		key, err := coerceValue(iter.Key().Interface(), targetType.Key())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("Key '%v': %s", iter.Key().Interface(), err)
+		}
+		element, err := coerceValue(iter.Value().Interface(), targetType.Elem())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("Key '%v': %s", iter.Key().Interface(), err)
+		}
+		keyValue := reflect.ValueOf(key)
+		elementValue := reflect.ValueOf(element)
+		if element == nil {
+			elementValue = reflect.Zero(targetType.Elem())
+		}
+		result.SetMapIndex(keyValue, elementValue)
+	}
+	return result, nil
+}
@@ -0,0 +1,134 @@
+package attribute
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestCoerceValue(t *testing.T) {
+	tests := []struct {
+		name          string
+		targetType    reflect.Type
+		value         any
+		expected      any
+		expectedError bool
+	}{
+		{name: "same type", targetType: reflect.TypeOf(""), value: "a", expected: "a"},
+		{name: "nil to zero value", targetType: reflect.TypeOf(0), value: nil, expected: 0},
+		{name: "string to int", targetType: reflect.TypeOf(0), value: "42", expected: 42},
+		{name: "invalid string to int", targetType: reflect.TypeOf(0), value: "4.2", expectedError: true},
+		{name: "string to uint", targetType: reflect.TypeOf(uint(0)), value: "42", expected: uint(42)},
+		{name: "negative string to uint", targetType: reflect.TypeOf(uint(0)), value: "-1", expectedError: true},
+		{name: "string to float", targetType: reflect.TypeOf(0.0), value: "4.5", expected: 4.5},
+		{name: "string to bool", targetType: reflect.TypeOf(false), value: "true", expected: true},
+		{name: "invalid string to bool", targetType: reflect.TypeOf(false), value: "yes please", expectedError: true},
+		{name: "int to string", targetType: reflect.TypeOf(""), value: 42, expected: "42"},
+		{name: "float to string", targetType: reflect.TypeOf(""), value: 4.5, expected: "4.5"},
+		{name: "bool to string", targetType: reflect.TypeOf(""), value: false, expected: "false"},
+		{name: "whole float to int", targetType: reflect.TypeOf(0), value: 42.0, expected: 42},
+		{name: "fractional float to int", targetType: reflect.TypeOf(0), value: 4.2, expectedError: true},
+		{name: "int overflow", targetType: reflect.TypeOf(int8(0)), value: 300, expectedError: true},
+		{name: "negative int to uint", targetType: reflect.TypeOf(uint(0)), value: -1, expectedError: true},
+		{name: "string to duration", targetType: DurationType, value: "1h30m", expected: 90 * time.Minute},
+		{name: "invalid string to duration", targetType: DurationType, value: "soon", expectedError: true},
+		{name: "int to duration as seconds", targetType: DurationType, value: 3600, expected: time.Hour},
+		{name: "float to duration as seconds", targetType: DurationType, value: 1.5, expected: 1500 * time.Millisecond},
+		{name: "duration overflow", targetType: DurationType, value: 1e300, expectedError: true},
+		{
+			name:       "list to typed slice",
+			targetType: reflect.TypeOf([]int{}),
+			value:      []any{1, "2", 3.0},
+			expected:   []int{1, 2, 3},
+		},
+		{
+			name:          "invalid list element",
+			targetType:    reflect.TypeOf([]int{}),
+			value:         []any{1, "two"},
+			expectedError: true,
+		},
+		{
+			name:       "map to typed map",
+			targetType: reflect.TypeOf(map[string]time.Duration{}),
+			value:      map[string]any{"backup": "24h", "retention": 60},
+			expected:   map[string]time.Duration{"backup": 24 * time.Hour, "retention": time.Minute},
+		},
+		{name: "not a list", targetType: reflect.TypeOf([]string{}), value: "a", expectedError: true},
+		{name: "json number to int64", targetType: reflect.TypeOf(int64(0)), value: json.Number("1152921504606846977"), expected: int64(1152921504606846977)},
+		{name: "json number to uint64", targetType: reflect.TypeOf(uint64(0)), value: json.Number("18446744073709551615"), expected: uint64(18446744073709551615)},
+		{name: "json number to float", targetType: reflect.TypeOf(0.0), value: json.Number("4.5"), expected: 4.5},
+		{name: "json number to string", targetType: reflect.TypeOf(""), value: json.Number("42"), expected: "42"},
+		{name: "json number to duration as seconds", targetType: DurationType, value: json.Number("3600"), expected: time.Hour},
+		{name: "fractional json number to int", targetType: reflect.TypeOf(0), value: json.Number("4.2"), expectedError: true},
+		{
+			name:       "json numbers in list",
+			targetType: reflect.TypeOf([]int64{}),
+			value:      []any{json.Number("1"), json.Number("1152921504606846977")},
+			expected:   []int64{1, 1152921504606846977},
+		},
+		{
+			name:       "json numbers in untyped map",
+			targetType: reflect.TypeOf(map[string]any{}),
+			value:      map[string]any{"a": json.Number("1"), "b": []any{json.Number("1.5")}},
+			expected:   map[string]any{"a": int64(1), "b": []any{1.5}},
+		},
+		{name: "cty string", targetType: reflect.TypeOf(""), value: cty.StringVal("a"), expected: "a"},
+		{name: "cty number to int", targetType: reflect.TypeOf(0), value: cty.NumberIntVal(42), expected: 42},
+		{
+			name:       "cty list to typed slice",
+			targetType: reflect.TypeOf([]string{}),
+			value:      cty.ListVal([]cty.Value{cty.StringVal("a"), cty.StringVal("b")}),
+			expected:   []string{"a", "b"},
+		},
+		{name: "cty null", targetType: reflect.TypeOf(""), value: cty.NullVal(cty.String), expected: ""},
+		{name: "cty unknown", targetType: reflect.TypeOf(""), value: cty.UnknownVal(cty.String), expectedError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attribute := &Attribute{Name: "test", Type: test.targetType}
+			coerced, err := attribute.CoerceValue(test.value)
+			if test.expectedError {
+				if err == nil {
+					t.Errorf("Expected error, got %#v", coerced)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(coerced, test.expected) {
+				t.Errorf("Expected %#v, got %#v", test.expected, coerced)
+			}
+		})
+	}
+}
+
+func TestCoerceValueObject(t *testing.T) {
+	attribute := &Attribute{
+		Name: "backup",
+		Type: ObjectType,
+		Fields: []Attribute{
+			{Name: "enabled", Type: reflect.TypeOf(false)},
+			{Name: "interval", Type: DurationType},
+		},
+	}
+	value := map[string]any{"enabled": "true", "interval": 3600, "notes": "nightly"}
+	coerced, err := attribute.CoerceValue(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{"enabled": true, "interval": time.Hour, "notes": "nightly"}
+	if !reflect.DeepEqual(coerced, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, coerced)
+	}
+	if value["enabled"] != "true" || value["interval"] != 3600 {
+		t.Errorf("Expected value to be unmodified, got %#v", value)
+	}
+
+	if _, err := attribute.CoerceValue(map[string]any{"interval": "soon"}); err == nil {
+		t.Error("Expected error for invalid field value")
+	}
+}
@@ -3,6 +3,7 @@ package attribute
 import (
 	"fmt"
 	"reflect"
+	"slices"
 )
 
 // defaultPriority Default priority for new Attribute Instances.
@@ -17,53 +18,331 @@ const defaultPriority int = 0
 // AttributeName Name of attribute
 type AttributeName string
 
+// MergeStrategy Determines how values for an attribute
+// from multiple instances are combined
+type MergeStrategy string
+
+const (
+	// The value from the instance with the highest priority is used.
+	// This is the default strategy
+	MergeStrategyHighestPriority MergeStrategy = "highest_priority"
+	// The first non-empty value, in merge order, is retained
+	MergeStrategyFirstNonEmpty MergeStrategy = "first_non_empty"
+	// Values of list (slice) attributes are appended together,
+	// without adding elements that are already present
+	MergeStrategyAppend MergeStrategy = "append"
+	// Values of map attributes are combined, with keys from
+	// the highest priority instance winning
+	MergeStrategyUnion MergeStrategy = "union"
+	// Differing non-empty values result in an error
+	MergeStrategyErrorOnConflict MergeStrategy = "error_on_conflict"
+)
+
 // Attribute Structure for holding information about a type
 // of attribute that will exist on an entity.
 type Attribute struct {
 	Name         AttributeName
 	Type         reflect.Type
 	DefaultValue any
+	// MergeStrategy Strategy used when merging instances.
+	// Defaults to MergeStrategyHighestPriority
+	MergeStrategy MergeStrategy
+	// EnumValues Permitted values for string attributes,
+	// or elements of string list attributes
+	EnumValues []string
+	// Fields Nested fields for object attributes (see ObjectType)
+	Fields []Attribute
+	// Validators Validators run against values, in addition to type checks
+	Validators []Validator
+	// ValidationSeverity Whether values failing validation are rejected.
+	// Defaults to ValidationSeverityError
+	ValidationSeverity ValidationSeverity
+}
+
+// GetMergeStrategy Returns the merge strategy, applying the default
+func (a *Attribute) GetMergeStrategy() MergeStrategy {
+	if a.MergeStrategy == "" {
+		return MergeStrategyHighestPriority
+	}
+	return a.MergeStrategy
 }
 
 // CreateInstance Returns a new instance of the Attribute
 // to be assigned to an Entity
 func (a *Attribute) CeateInstance() AttributeInstance {
+	return a.CreateInstanceWithPriority(defaultPriority)
+}
+
+// CreateInstanceWithPriority Returns a new instance of the Attribute
+// with the given priority, to be assigned to an Entity
+func (a *Attribute) CreateInstanceWithPriority(priority int) AttributeInstance {
 	return AttributeInstance{
 		Attribute: a,
-		Priority:  defaultPriority,
+		Priority:  priority,
 		Value:     a.DefaultValue,
 	}
 }
 
 // AttributeInstance An instance of an attribute, to be assigned to an Entity
 type AttributeInstance struct {
-	Attribute *Attribute
-	Priority  int
-	Value     any
+	Attribute  *Attribute
+	Priority   int
+	Value      any
+	Provenance Provenance
+	// Candidates Values from each instance that has been merged
+	// into this instance, including those that were not used
+	Candidates []AttributeCandidate
+	// ValidationWarnings Validation failures of the value, for
+	// attributes with ValidationSeverityWarning
+	ValidationWarnings []*ValidationError
 }
 
+// SetValue Set the value of the instance, after checking its type
+// and running the attribute's validators. Values failing validation
+// are rejected, unless the attribute has ValidationSeverityWarning,
+// in which case the failures are recorded in ValidationWarnings
 func (ai *AttributeInstance) SetValue(value any) error {
-	if valueType := reflect.TypeOf(value); ai.Attribute.Type != valueType {
-		return fmt.Errorf("Cannot set '%s' with value '%s'. Expected type: %s, Actual type: %s", ai.Attribute.Name, value, ai.Attribute.Type, valueType)
+	if err := ai.Attribute.ValidateValue(value); err != nil {
+		return err
 	}
+	validationErrors := ai.Attribute.RunValidators(value)
+	for _, validationError := range validationErrors {
+		if validationError.Severity == ValidationSeverityError {
+			return validationError
+		}
+	}
+	ai.ValidationWarnings = validationErrors
 	ai.Value = value
 	return nil
 }
 
-func (ai *AttributeInstance) MergeAttribute(new *AttributeInstance) {
-	if new == nil {
-		return
+// ApplyDefaultPriority Set the priority of the instance,
+// if it has not already been set
+func (ai *AttributeInstance) ApplyDefaultPriority(priority int) {
+	if ai.Priority == defaultPriority {
+		ai.Priority = priority
 	}
+	for i := range ai.Candidates {
+		if ai.Candidates[i].Priority == defaultPriority {
+			ai.Candidates[i].Priority = priority
+		}
+	}
+}
 
-	if ai.Attribute != new.Attribute {
-		return
+// ApplyDefaultSource Set the source of the instance provenance,
+// if it has not already been set
+func (ai *AttributeInstance) ApplyDefaultSource(source string) {
+	if ai.Provenance.Source == "" {
+		ai.Provenance.Source = source
 	}
+	for i := range ai.Candidates {
+		if ai.Candidates[i].Provenance.Source == "" {
+			ai.Candidates[i].Provenance.Source = source
+		}
+	}
+}
 
-	if ai.Priority >= new.Priority {
-		return
+// GetCandidates Returns all values that have been provided for the
+// instance. An instance that has not been merged returns itself
+func (ai *AttributeInstance) GetCandidates() []AttributeCandidate {
+	if len(ai.Candidates) > 0 {
+		return ai.Candidates
 	}
+	return []AttributeCandidate{{
+		Value:      ai.Value,
+		Priority:   ai.Priority,
+		Provenance: ai.Provenance,
+	}}
+}
 
-	// Merge attribute
+// GetConflictingCandidates Returns the candidates that provided differing,
+// non-empty, values at the priority of the selected value. Conflicts are
+// only reported for attributes using MergeStrategyHighestPriority, as other
+// strategies combine or explicitly select between values
+func (ai *AttributeInstance) GetConflictingCandidates() []AttributeCandidate {
+	if ai.Attribute == nil || ai.Attribute.GetMergeStrategy() != MergeStrategyHighestPriority {
+		return nil
+	}
+	var conflicting []AttributeCandidate
+	for _, candidate := range ai.Candidates {
+		if candidate.Priority == ai.Priority && !isEmptyValue(candidate.Value) {
+			conflicting = append(conflicting, candidate)
+		}
+	}
+	for _, candidate := range conflicting {
+		if !reflect.DeepEqual(candidate.Value, conflicting[0].Value) {
+			return conflicting
+		}
+	}
+	return nil
+}
+
+// HasConflict Whether multiple values were provided at the same priority
+func (ai *AttributeInstance) HasConflict() bool {
+	return len(ai.GetConflictingCandidates()) > 0
+}
+
+// takeValue Replace the value of the instance with that of another instance
+func (ai *AttributeInstance) takeValue(new *AttributeInstance) {
+	ai.ValidationWarnings = new.ValidationWarnings
 	ai.Value = new.Value
 	ai.Priority = new.Priority
+	ai.Provenance = new.Provenance
+}
+
+// takeProvenance Use the provenance of the highest priority instance
+// for a value combined from both instances
+func (ai *AttributeInstance) takeProvenance(new *AttributeInstance) {
+	if new.Priority > ai.Priority {
+		ai.Priority = new.Priority
+		ai.Provenance = new.Provenance
+	}
+}
+
+// isEmptyValue Determine whether a value is considered unset.
+// Only nil, empty strings and empty lists and maps are empty;
+// false, 0 and zero durations are values in their own right
+func isEmptyValue(value any) bool {
+	if value == nil {
+		return true
+	}
+	reflectValue := reflect.ValueOf(value)
+	switch reflectValue.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return reflectValue.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return reflectValue.IsNil()
+	}
+	return false
+}
+
+// IsEmpty Whether the instance holds an empty value
+func (ai *AttributeInstance) IsEmpty() bool {
+	return isEmptyValue(ai.Value)
+}
+
+// MergeAttribute Merge another instance of the same attribute
+// into the instance, using the attribute's merge strategy
+func (ai *AttributeInstance) MergeAttribute(new *AttributeInstance) error {
+	if new == nil {
+		return nil
+	}
+
+	if ai.Attribute == nil || new.Attribute == nil || ai.Attribute.Name != new.Attribute.Name {
+		return nil
+	}
+
+	candidates := append(slices.Clone(ai.GetCandidates()), new.GetCandidates()...)
+
+	switch strategy := ai.Attribute.GetMergeStrategy(); strategy {
+	case MergeStrategyHighestPriority:
+		if ai.Priority < new.Priority {
+			ai.takeValue(new)
+		}
+
+	case MergeStrategyFirstNonEmpty:
+		if ai.IsEmpty() && !new.IsEmpty() {
+			ai.takeValue(new)
+		}
+
+	case MergeStrategyAppend:
+		merged, err := appendValues(ai.Value, new.Value)
+		if err != nil {
+			return fmt.Errorf("MergeAttribute: %s: %s", ai.Attribute.Name, err)
+		}
+		ai.Value = merged
+		ai.takeProvenance(new)
+
+	case MergeStrategyUnion:
+		var merged any
+		var err error
+		if ai.Priority > new.Priority {
+			merged, err = unionValues(new.Value, ai.Value)
+		} else {
+			merged, err = unionValues(ai.Value, new.Value)
+		}
+		if err != nil {
+			return fmt.Errorf("MergeAttribute: %s: %s", ai.Attribute.Name, err)
+		}
+		ai.Value = merged
+		ai.takeProvenance(new)
+
+	case MergeStrategyErrorOnConflict:
+		if new.IsEmpty() {
+			break
+		}
+		if !ai.IsEmpty() && !reflect.DeepEqual(ai.Value, new.Value) {
+			return fmt.Errorf("MergeAttribute: Conflicting values for attribute %s: '%v' (%s) and '%v' (%s)", ai.Attribute.Name, ai.Value, ai.Provenance, new.Value, new.Provenance)
+		}
+		if ai.IsEmpty() {
+			ai.takeValue(new)
+		} else {
+			ai.takeProvenance(new)
+		}
+
+	default:
+		return fmt.Errorf("MergeAttribute: Unknown merge strategy for attribute %s: %s", ai.Attribute.Name, strategy)
+	}
+	ai.Candidates = candidates
+	return nil
+}
+
+// appendValues Returns a new slice containing the elements of both values.
+// Elements of the new value that are already present are not added again,
+// e.g. a dependency reported by two sources
+func appendValues(existing any, new any) (any, error) {
+	if isEmptyValue(new) {
+		return existing, nil
+	}
+	if isEmptyValue(existing) {
+		return new, nil
+	}
+	existingValue := reflect.ValueOf(existing)
+	newValue := reflect.ValueOf(new)
+	if existingValue.Kind() != reflect.Slice || existingValue.Type() != newValue.Type() {
+		return nil, fmt.Errorf("Cannot append values of type %s and %s", existingValue.Type(), newValue.Type())
+	}
+	merged := reflect.MakeSlice(existingValue.Type(), 0, existingValue.Len()+newValue.Len())
+	merged = reflect.AppendSlice(merged, existingValue)
+	for i := 0; i < newValue.Len(); i++ {
+		element := newValue.Index(i)
+		if !containsElement(merged, element) {
+			merged = reflect.Append(merged, element)
+		}
+	}
+	return merged.Interface(), nil
+}
+
+// containsElement Whether a slice contains an element equal to the given element
+func containsElement(slice reflect.Value, element reflect.Value) bool {
+	for i := 0; i < slice.Len(); i++ {
+		if reflect.DeepEqual(slice.Index(i).Interface(), element.Interface()) {
+			return true
+		}
+	}
+	return false
+}
+
+// unionValues Returns a new map containing the keys of both values.
+// Keys from the override value take precedence
+func unionValues(base any, override any) (any, error) {
+	if isEmptyValue(override) {
+		return base, nil
+	}
+	if isEmptyValue(base) {
+		return override, nil
+	}
+	baseValue := reflect.ValueOf(base)
+	overrideValue := reflect.ValueOf(override)
+	if baseValue.Kind() != reflect.Map || baseValue.Type() != overrideValue.Type() {
+		return nil, fmt.Errorf("Cannot union values of type %s and %s", baseValue.Type(), overrideValue.Type())
+	}
+	merged := reflect.MakeMapWithSize(baseValue.Type(), baseValue.Len()+overrideValue.Len())
+	for _, value := range []reflect.Value{baseValue, overrideValue} {
+		iter := value.MapRange()
+		for iter.Next() {
+			merged.SetMapIndex(iter.Key(), iter.Value())
+		}
+	}
+	return merged.Interface(), nil
 }
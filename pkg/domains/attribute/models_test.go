@@ -0,0 +1,201 @@
+package attribute
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMergeAttribute(t *testing.T) {
+	tests := []struct {
+		name          string
+		strategy      MergeStrategy
+		existing      any
+		priority      int
+		new           any
+		newPriority   int
+		expected      any
+		expectedError bool
+	}{
+		{
+			name:     "highest priority keeps higher priority value",
+			existing: "a", priority: 2,
+			new: "b", newPriority: 1,
+			expected: "a",
+		},
+		{
+			name:     "highest priority takes higher priority value",
+			existing: "a", priority: 1,
+			new: "b", newPriority: 2,
+			expected: "b",
+		},
+		{
+			name:     "highest priority keeps existing value at equal priority",
+			existing: "a", priority: 1,
+			new: "b", newPriority: 1,
+			expected: "a",
+		},
+		{
+			name:     "first non-empty takes value over empty string",
+			strategy: MergeStrategyFirstNonEmpty,
+			existing: "", priority: 2,
+			new: "b", newPriority: 1,
+			expected: "b",
+		},
+		{
+			name:     "first non-empty keeps false",
+			strategy: MergeStrategyFirstNonEmpty,
+			existing: false, priority: 1,
+			new: true, newPriority: 2,
+			expected: false,
+		},
+		{
+			name:     "first non-empty keeps zero",
+			strategy: MergeStrategyFirstNonEmpty,
+			existing: 0, priority: 1,
+			new: 5, newPriority: 2,
+			expected: 0,
+		},
+		{
+			name:     "first non-empty keeps zero duration",
+			strategy: MergeStrategyFirstNonEmpty,
+			existing: time.Duration(0), priority: 1,
+			new: time.Hour, newPriority: 2,
+			expected: time.Duration(0),
+		},
+		{
+			name:     "first non-empty takes value over nil",
+			strategy: MergeStrategyFirstNonEmpty,
+			existing: nil, priority: 1,
+			new: 5, newPriority: 0,
+			expected: 5,
+		},
+		{
+			name:     "append lists",
+			strategy: MergeStrategyAppend,
+			existing: []string{"a"}, priority: 1,
+			new: []string{"b", "c"}, newPriority: 2,
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name:     "append lists without duplicates",
+			strategy: MergeStrategyAppend,
+			existing: []string{"dns", "db"}, priority: 1,
+			new: []string{"db", "cache", "dns", "cache"}, newPriority: 2,
+			expected: []string{"dns", "db", "cache"},
+		},
+		{
+			name:     "append to empty list",
+			strategy: MergeStrategyAppend,
+			existing: []string{}, priority: 1,
+			new: []string{"b"}, newPriority: 2,
+			expected: []string{"b"},
+		},
+		{
+			name:     "append mismatched types",
+			strategy: MergeStrategyAppend,
+			existing: []string{"a"}, priority: 1,
+			new: []int{1}, newPriority: 2,
+			expectedError: true,
+		},
+		{
+			name:     "union maps with higher priority keys winning",
+			strategy: MergeStrategyUnion,
+			existing: map[string]string{"a": "1", "b": "1"}, priority: 2,
+			new: map[string]string{"b": "2", "c": "2"}, newPriority: 1,
+			expected: map[string]string{"a": "1", "b": "1", "c": "2"},
+		},
+		{
+			name:     "union maps with new higher priority keys winning",
+			strategy: MergeStrategyUnion,
+			existing: map[string]string{"a": "1", "b": "1"}, priority: 1,
+			new: map[string]string{"b": "2"}, newPriority: 2,
+			expected: map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			name:     "error on conflict with equal values",
+			strategy: MergeStrategyErrorOnConflict,
+			existing: "a", priority: 1,
+			new: "a", newPriority: 2,
+			expected: "a",
+		},
+		{
+			name:     "error on conflict fills empty value",
+			strategy: MergeStrategyErrorOnConflict,
+			existing: "", priority: 1,
+			new: "a", newPriority: 2,
+			expected: "a",
+		},
+		{
+			name:     "error on conflict with differing values",
+			strategy: MergeStrategyErrorOnConflict,
+			existing: "a", priority: 1,
+			new: "b", newPriority: 2,
+			expectedError: true,
+		},
+		{
+			name:     "error on conflict between false and true",
+			strategy: MergeStrategyErrorOnConflict,
+			existing: false, priority: 1,
+			new: true, newPriority: 2,
+			expectedError: true,
+		},
+		{
+			name:     "unknown strategy",
+			strategy: "unknown",
+			existing: "a", priority: 1,
+			new: "b", newPriority: 2,
+			expectedError: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attribute := &Attribute{Name: "test", MergeStrategy: test.strategy}
+			existing := AttributeInstance{Attribute: attribute, Value: test.existing, Priority: test.priority}
+			new := AttributeInstance{Attribute: attribute, Value: test.new, Priority: test.newPriority}
+			err := existing.MergeAttribute(&new)
+			if test.expectedError {
+				if err == nil {
+					t.Errorf("Expected error, got value %#v", existing.Value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(existing.Value, test.expected) {
+				t.Errorf("Expected %#v, got %#v", test.expected, existing.Value)
+			}
+			if candidates := existing.GetCandidates(); len(candidates) != 2 {
+				t.Errorf("Expected 2 candidates, got %d", len(candidates))
+			}
+		})
+	}
+}
+
+func TestIsEmptyValue(t *testing.T) {
+	var nilMap map[string]string
+	var nilPointer *string
+	tests := []struct {
+		value    any
+		expected bool
+	}{
+		{nil, true},
+		{"", true},
+		{[]string{}, true},
+		{map[string]string{}, true},
+		{nilMap, true},
+		{nilPointer, true},
+		{"a", false},
+		{false, false},
+		{0, false},
+		{0.0, false},
+		{time.Duration(0), false},
+		{[]string{"a"}, false},
+	}
+	for _, test := range tests {
+		if empty := isEmptyValue(test.value); empty != test.expected {
+			t.Errorf("isEmptyValue(%#v): Expected %t, got %t", test.value, test.expected, empty)
+		}
+	}
+}
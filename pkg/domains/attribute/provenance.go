@@ -0,0 +1,64 @@
+package attribute
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Provenance Details of where an attribute value originated
+type Provenance struct {
+	// Source Name of the entity source that provided the value
+	Source string `json:"source,omitempty" yaml:"source,omitempty"`
+	// File Path of the file that the value was read from
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+	// Line Line number within File
+	Line int `json:"line,omitempty" yaml:"line,omitempty"`
+	// Commit Git commit that File was read from
+	Commit string `json:"commit,omitempty" yaml:"commit,omitempty"`
+	// Url URL that the value was obtained from
+	Url string `json:"url,omitempty" yaml:"url,omitempty"`
+}
+
+// IsZero Whether no provenance information is present
+func (p Provenance) IsZero() bool {
+	return p == Provenance{}
+}
+
+// Origin Returns a description of the origin of the value,
+// without the source name
+func (p Provenance) Origin() string {
+	var parts []string
+	if p.File != "" {
+		if p.Line > 0 {
+			parts = append(parts, fmt.Sprintf("%s:%d", p.File, p.Line))
+		} else {
+			parts = append(parts, p.File)
+		}
+	}
+	if p.Commit != "" {
+		parts = append(parts, fmt.Sprintf("commit %s", p.Commit))
+	}
+	if p.Url != "" {
+		parts = append(parts, p.Url)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (p Provenance) String() string {
+	source := p.Source
+	if source == "" {
+		source = "unknown"
+	}
+	if origin := p.Origin(); origin != "" {
+		return fmt.Sprintf("%s (%s)", source, origin)
+	}
+	return source
+}
+
+// AttributeCandidate A value that was provided for an attribute
+// by a single source, prior to merging
+type AttributeCandidate struct {
+	Value      any
+	Priority   int
+	Provenance Provenance
+}
@@ -34,3 +34,17 @@ func (af *AttributeFactory) GetAttributeByName(name AttributeName) *Attribute {
 	}
 	return nil
 }
+
+// CoerceValue Look up an attribute by name and convert a dynamically
+// typed value to the attribute's type. See Attribute.CoerceValue
+func (af *AttributeFactory) CoerceValue(name AttributeName, value any) (*Attribute, any, error) {
+	attribute := af.GetAttributeByName(name)
+	if attribute == nil {
+		return nil, nil, fmt.Errorf("CoerceValue: Attribute %s is not registered", name)
+	}
+	coerced, err := attribute.CoerceValue(value)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attribute, coerced, nil
+}
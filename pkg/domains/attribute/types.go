@@ -0,0 +1,132 @@
+package attribute
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// ObjectType Type of attributes holding nested objects
+var ObjectType reflect.Type = reflect.TypeOf(map[string]any{})
+
+// DurationType Type of attributes holding durations
+var DurationType reflect.Type = reflect.TypeOf(time.Duration(0))
+
+// NewListAttribute Returns an attribute holding a list of elementType values.
+// Lists from multiple sources are appended
+func NewListAttribute(name AttributeName, elementType reflect.Type) Attribute {
+	listType := reflect.SliceOf(elementType)
+	return Attribute{
+		Name:          name,
+		Type:          listType,
+		DefaultValue:  reflect.MakeSlice(listType, 0, 0).Interface(),
+		MergeStrategy: MergeStrategyAppend,
+	}
+}
+
+// NewMapAttribute Returns an attribute holding a map of string keys to valueType values.
+// Maps from multiple sources are combined
+func NewMapAttribute(name AttributeName, valueType reflect.Type) Attribute {
+	mapType := reflect.MapOf(reflect.TypeOf(""), valueType)
+	return Attribute{
+		Name:          name,
+		Type:          mapType,
+		DefaultValue:  reflect.MakeMap(mapType).Interface(),
+		MergeStrategy: MergeStrategyUnion,
+	}
+}
+
+// NewEnumAttribute Returns a string attribute that only accepts the given values
+func NewEnumAttribute(name AttributeName, values ...string) Attribute {
+	return Attribute{
+		Name:         name,
+		Type:         reflect.TypeOf(""),
+		DefaultValue: "",
+		EnumValues:   values,
+	}
+}
+
+// NewDurationAttribute Returns an attribute holding a time.Duration
+func NewDurationAttribute(name AttributeName) Attribute {
+	return Attribute{
+		Name:         name,
+		Type:         DurationType,
+		DefaultValue: time.Duration(0),
+	}
+}
+
+// NewObjectAttribute Returns an attribute holding a nested object,
+// represented as a map[string]any, with the given fields
+func NewObjectAttribute(name AttributeName, fields ...Attribute) Attribute {
+	return Attribute{
+		Name:         name,
+		Type:         ObjectType,
+		DefaultValue: map[string]any{},
+		Fields:       fields,
+	}
+}
+
+// GetField Returns the nested field of an object attribute
+func (a *Attribute) GetField(name AttributeName) *Attribute {
+	for i := range a.Fields {
+		if a.Fields[i].Name == name {
+			return &a.Fields[i]
+		}
+	}
+	return nil
+}
+
+// ValidateValue Validate that a value can be assigned to the attribute,
+// checking its type, enum membership and nested fields.
+// Empty values are always accepted, as these represent an unset attribute
+func (a *Attribute) ValidateValue(value any) error {
+	if valueType := reflect.TypeOf(value); a.Type != valueType {
+		return fmt.Errorf("Cannot set '%s' with value '%v'. Expected type: %s, Actual type: %s", a.Name, value, a.Type, valueType)
+	}
+	if isEmptyValue(value) {
+		return nil
+	}
+
+	if len(a.EnumValues) > 0 {
+		if err := a.validateEnum(value); err != nil {
+			return err
+		}
+	}
+
+	if a.Type == ObjectType && len(a.Fields) > 0 {
+		for key, fieldValue := range value.(map[string]any) {
+			field := a.GetField(AttributeName(key))
+			if field == nil {
+				return fmt.Errorf("Cannot set '%s': Unknown field '%s'", a.Name, key)
+			}
+			if err := field.ValidateValue(fieldValue); err != nil {
+				return fmt.Errorf("Cannot set '%s': %s", a.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateEnum Check that a string value, or each element of a
+// list of strings, is one of the permitted values
+func (a *Attribute) validateEnum(value any) error {
+	reflectValue := reflect.ValueOf(value)
+	var values []string
+	switch {
+	case reflectValue.Kind() == reflect.String:
+		values = []string{reflectValue.String()}
+	case reflectValue.Kind() == reflect.Slice && reflectValue.Type().Elem().Kind() == reflect.String:
+		for i := 0; i < reflectValue.Len(); i++ {
+			values = append(values, reflectValue.Index(i).String())
+		}
+	default:
+		return fmt.Errorf("Cannot set '%s': Enum values are only supported for string attributes", a.Name)
+	}
+	for _, v := range values {
+		if !slices.Contains(a.EnumValues, v) {
+			return fmt.Errorf("Cannot set '%s' with value '%s'. Expected one of: %v", a.Name, v, a.EnumValues)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package attribute
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ValidationSeverity Determines whether a failed validation prevents
+// the value from being set
+type ValidationSeverity string
+
+const (
+	// The value is rejected. This is the default severity
+	ValidationSeverityError ValidationSeverity = "error"
+	// The value is set and a warning is recorded on the instance
+	ValidationSeverityWarning ValidationSeverity = "warning"
+)
+
+// Validator Validates the value of an attribute, beyond its type.
+// For list and map attributes, validators are run against each element
+type Validator interface {
+	Validate(value any) error
+}
+
+// ValidationError A value that failed validation
+type ValidationError struct {
+	Attribute AttributeName
+	Value     any
+	Severity  ValidationSeverity
+	Err       error
+}
+
+func (v *ValidationError) Error() string {
+	return fmt.Sprintf("Invalid value '%v' for attribute %s: %s", v.Value, v.Attribute, v.Err)
+}
+
+func (v *ValidationError) Unwrap() error {
+	return v.Err
+}
+
+// GetValidationSeverity Returns the validation severity, applying the default
+func (a *Attribute) GetValidationSeverity() ValidationSeverity {
+	if a.ValidationSeverity == "" {
+		return ValidationSeverityError
+	}
+	return a.ValidationSeverity
+}
+
+// RunValidators Run the attribute's validators against a value.
+// For object attributes, the validators of each field are also run
+// against the value of the field, with the field named as
+// object.field in the returned errors. Empty values are not validated
+func (a *Attribute) RunValidators(value any) []*ValidationError {
+	if isEmptyValue(value) {
+		return nil
+	}
+	validationErrors := a.runOwnValidators(value)
+	if a.Type == ObjectType && len(a.Fields) > 0 {
+		object, _ := value.(map[string]any)
+		for i := range a.Fields {
+			field := &a.Fields[i]
+			fieldValue, ok := object[string(field.Name)]
+			if !ok {
+				continue
+			}
+			for _, validationError := range field.RunValidators(fieldValue) {
+				validationError.Attribute = a.Name + "." + validationError.Attribute
+				validationErrors = append(validationErrors, validationError)
+			}
+		}
+	}
+	return validationErrors
+}
+
+// runOwnValidators Run the attribute's validators against a value,
+// or against each element of a list or map value
+func (a *Attribute) runOwnValidators(value any) []*ValidationError {
+	if len(a.Validators) == 0 {
+		return nil
+	}
+	var elements []any
+	reflectValue := reflect.ValueOf(value)
+	switch reflectValue.Kind() {
+	case reflect.Slice:
+		for i := 0; i < reflectValue.Len(); i++ {
+			elements = append(elements, reflectValue.Index(i).Interface())
+		}
+	case reflect.Map:
+		iter := reflectValue.MapRange()
+		for iter.Next() {
+			elements = append(elements, iter.Value().Interface())
+		}
+	default:
+		elements = []any{value}
+	}
+
+	var validationErrors []*ValidationError
+	for _, element := range elements {
+		for _, validator := range a.Validators {
+			if err := validator.Validate(element); err != nil {
+				validationErrors = append(validationErrors, &ValidationError{
+					Attribute: a.Name,
+					Value:     element,
+					Severity:  a.GetValidationSeverity(),
+					Err:       err,
+				})
+			}
+		}
+	}
+	return validationErrors
+}
+
+func validateString(value any) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("Expected string, got %T", value)
+	}
+	return s, nil
+}
+
+// IpAddressValidator Validates that a value is an IPv4 or IPv6 address
+type IpAddressValidator struct{}
+
+func (v IpAddressValidator) Validate(value any) error {
+	s, err := validateString(value)
+	if err != nil {
+		return err
+	}
+	if _, err := netip.ParseAddr(s); err != nil {
+		return fmt.Errorf("Not a valid IP address")
+	}
+	return nil
+}
+
+// CidrValidator Validates that a value is a network in CIDR notation
+type CidrValidator struct{}
+
+func (v CidrValidator) Validate(value any) error {
+	s, err := validateString(value)
+	if err != nil {
+		return err
+	}
+	if _, err := netip.ParsePrefix(s); err != nil {
+		return fmt.Errorf("Not a valid CIDR")
+	}
+	return nil
+}
+
+// UrlValidator Validates that a value is an absolute URL.
+// If Schemes is empty, http and https are permitted
+type UrlValidator struct {
+	Schemes []string
+}
+
+func (v UrlValidator) Validate(value any) error {
+	s, err := validateString(value)
+	if err != nil {
+		return err
+	}
+	parsedUrl, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("Not a valid URL: %s", err)
+	}
+	schemes := v.Schemes
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+	if !slices.Contains(schemes, parsedUrl.Scheme) {
+		return fmt.Errorf("URL scheme must be one of: %s", strings.Join(schemes, ", "))
+	}
+	if parsedUrl.Host == "" {
+		return fmt.Errorf("URL must be absolute")
+	}
+	return nil
+}
+
+var hostnameLabelRegex = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// HostnameValidator Validates that a value is a valid (RFC 1123) hostname
+type HostnameValidator struct{}
+
+func (v HostnameValidator) Validate(value any) error {
+	s, err := validateString(value)
+	if err != nil {
+		return err
+	}
+	if len(s) > 253 {
+		return fmt.Errorf("Hostname exceeds 253 characters")
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(s, "."), ".") {
+		if !hostnameLabelRegex.MatchString(label) {
+			return fmt.Errorf("Not a valid hostname")
+		}
+	}
+	return nil
+}
+
+// RegexValidator Validates that a value matches a regular expression
+type RegexValidator struct {
+	Pattern *regexp.Regexp
+}
+
+func (v RegexValidator) Validate(value any) error {
+	if v.Pattern == nil {
+		return fmt.Errorf("RegexValidator: Pattern is nil")
+	}
+	s, err := validateString(value)
+	if err != nil {
+		return err
+	}
+	if !v.Pattern.MatchString(s) {
+		return fmt.Errorf("Does not match pattern %s", v.Pattern)
+	}
+	return nil
+}
+
+// RangeValidator Validates that a numeric value is between Min and Max, inclusive
+type RangeValidator struct {
+	Min float64
+	Max float64
+}
+
+func (v RangeValidator) Validate(value any) error {
+	var number float64
+	reflectValue := reflect.ValueOf(value)
+	switch {
+	case reflectValue.CanInt():
+		number = float64(reflectValue.Int())
+	case reflectValue.CanUint():
+		number = float64(reflectValue.Uint())
+	case reflectValue.CanFloat():
+		number = reflectValue.Float()
+	default:
+		return fmt.Errorf("Expected number, got %T", value)
+	}
+	if number < v.Min || number > v.Max {
+		return fmt.Errorf("Must be between %v and %v", v.Min, v.Max)
+	}
+	return nil
+}
+
+// PortValidator Returns a validator for TCP/UDP port numbers
+func PortValidator() RangeValidator {
+	return RangeValidator{Min: 1, Max: 65535}
+}
@@ -0,0 +1,90 @@
+package attribute
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestRunValidators(t *testing.T) {
+	backup := &Attribute{
+		Name: "backup",
+		Type: ObjectType,
+		Fields: []Attribute{
+			{Name: "target", Type: reflect.TypeOf(""), Validators: []Validator{UrlValidator{Schemes: []string{"s3"}}}},
+			{Name: "port", Type: reflect.TypeOf(0), Validators: []Validator{PortValidator()}, ValidationSeverity: ValidationSeverityWarning},
+			{
+				Name: "replica",
+				Type: ObjectType,
+				Fields: []Attribute{
+					{Name: "host", Type: reflect.TypeOf(""), Validators: []Validator{HostnameValidator{}}},
+				},
+			},
+		},
+	}
+	tests := []struct {
+		name      string
+		attribute *Attribute
+		value     any
+		expected  []string
+	}{
+		{
+			name:      "valid value",
+			attribute: &Attribute{Name: "ip_address", Validators: []Validator{IpAddressValidator{}}},
+			value:     "10.0.0.1",
+		},
+		{
+			name:      "invalid value",
+			attribute: &Attribute{Name: "ip_address", Validators: []Validator{IpAddressValidator{}}},
+			value:     "10.0.0",
+			expected:  []string{"error: Invalid value '10.0.0' for attribute ip_address: Not a valid IP address"},
+		},
+		{
+			name:      "empty value",
+			attribute: &Attribute{Name: "ip_address", Validators: []Validator{IpAddressValidator{}}},
+			value:     "",
+		},
+		{
+			name:      "list elements",
+			attribute: &Attribute{Name: "ports", Validators: []Validator{PortValidator()}},
+			value:     []int{22, 0, 443},
+			expected:  []string{"error: Invalid value '0' for attribute ports: Must be between 1 and 65535"},
+		},
+		{
+			name:      "object fields",
+			attribute: backup,
+			value:     map[string]any{"target": "s3://backups/web", "port": 443, "replica": map[string]any{"host": "backup-01"}},
+		},
+		{
+			name:      "invalid object fields",
+			attribute: backup,
+			value: map[string]any{
+				"target":  "https://backups.example.com",
+				"port":    70000,
+				"replica": map[string]any{"host": "backup_01"},
+			},
+			expected: []string{
+				"error: Invalid value 'https://backups.example.com' for attribute backup.target: URL scheme must be one of: s3",
+				"warning: Invalid value '70000' for attribute backup.port: Must be between 1 and 65535",
+				"error: Invalid value 'backup_01' for attribute backup.replica.host: Not a valid hostname",
+			},
+		},
+		{
+			name:      "missing object fields",
+			attribute: backup,
+			value:     map[string]any{"notes": "nightly"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errors := []string{}
+			for _, validationError := range test.attribute.RunValidators(test.value) {
+				errors = append(errors, string(validationError.Severity)+": "+validationError.Error())
+			}
+			expected := append([]string{}, test.expected...)
+			if !slices.Equal(errors, expected) {
+				t.Errorf("Expected errors %q, got %q", expected, errors)
+			}
+		})
+	}
+}
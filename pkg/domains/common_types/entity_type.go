@@ -12,14 +12,52 @@ var (
 	EntityService metadata.EntityType = "service"
 )
 
+const (
+	CriticalityLow    string = "low"
+	CriticalityMedium string = "medium"
+	CriticalityHigh   string = "high"
+)
+
 var AttributeUrl = attribute.Attribute{
 	Name:         "url",
 	Type:         reflect.TypeOf(""),
 	DefaultValue: "",
+	Validators: []attribute.Validator{
+		attribute.UrlValidator{},
+	},
 }
 
 var AttributeIpAddress attribute.Attribute = attribute.Attribute{
 	Name:         "ip_address",
 	Type:         reflect.TypeOf(""),
 	DefaultValue: "",
+	Validators: []attribute.Validator{
+		attribute.IpAddressValidator{},
+	},
 }
+
+var AttributeCriticality = attribute.NewEnumAttribute("criticality", CriticalityLow, CriticalityMedium, CriticalityHigh)
+
+var AttributeStorage = attribute.NewObjectAttribute(
+	"storage",
+	attribute.Attribute{
+		Name:         "volume",
+		Type:         reflect.TypeOf(""),
+		DefaultValue: "",
+	},
+	attribute.Attribute{
+		Name:         "participates_in_host_backup",
+		Type:         reflect.TypeOf(false),
+		DefaultValue: false,
+	},
+)
+
+// AttributeRecoveryTimeObjective Maximum acceptable time to restore the entity
+var AttributeRecoveryTimeObjective = attribute.NewDurationAttribute("rto")
+
+// AttributePorts Map of port names to port numbers
+var AttributePorts = func() attribute.Attribute {
+	ports := attribute.NewMapAttribute("ports", reflect.TypeOf(0))
+	ports.Validators = []attribute.Validator{attribute.PortValidator()}
+	return ports
+}()
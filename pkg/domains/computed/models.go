@@ -0,0 +1,107 @@
+package computed
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+)
+
+// ComputedAttributeSource Source name used in the provenance of computed values
+const ComputedAttributeSource string = "computed"
+
+// ComputeFunc Computes the value of an attribute for an entity
+type ComputeFunc func(entity *metadata.Entity) (any, error)
+
+// ComputedAttribute An attribute whose value is derived from other
+// attributes, after entities from all sources have been merged
+type ComputedAttribute struct {
+	Attribute *attribute.Attribute
+	// DependsOn Attributes used to compute the value. The value is only
+	// computed when all dependencies are set, and computed dependencies
+	// are evaluated first
+	DependsOn []attribute.AttributeName
+	// EntityTypes Entity types to compute the attribute for.
+	// Computed for all entity types if empty
+	EntityTypes []metadata.EntityType
+	Compute     ComputeFunc
+	// Override Replace values for the attribute provided by sources
+	Override bool
+}
+
+// expressionEntity Entity data available to template expressions
+type expressionEntity struct {
+	Name   string
+	Type   string
+	entity *metadata.Entity
+}
+
+// Get Get an attribute value by name (returns empty string if not found)
+func (e *expressionEntity) Get(attributeName string) any {
+	if attributeInstance := e.entity.GetAttributeByName(attribute.AttributeName(attributeName)); attributeInstance != nil {
+		return attributeInstance.Value
+	}
+	return ""
+}
+
+// NewTemplateComputedAttribute Returns a computed attribute whose value is
+// rendered from a Go template expression, e.g. `{{.Name}}.{{.Get "domain"}}`.
+// The rendered string is coerced to the attribute's type
+func NewTemplateComputedAttribute(attr *attribute.Attribute, expression string, dependsOn ...attribute.AttributeName) (*ComputedAttribute, error) {
+	if attr == nil {
+		return nil, fmt.Errorf("NewTemplateComputedAttribute: attribute is nil")
+	}
+	parsedTemplate, err := template.New(string(attr.Name)).Option("missingkey=error").Parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("NewTemplateComputedAttribute: Error parsing expression for %s: %s", attr.Name, err)
+	}
+	return &ComputedAttribute{
+		Attribute: attr,
+		DependsOn: dependsOn,
+		Compute: func(entity *metadata.Entity) (any, error) {
+			var b bytes.Buffer
+			err := parsedTemplate.Execute(&b, &expressionEntity{
+				Name:   string(entity.GetName()),
+				Type:   string(entity.GetType()),
+				entity: entity,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return attr.CoerceValue(b.String())
+		},
+	}, nil
+}
+
+// NewMappingComputedAttribute Returns a computed attribute whose value is
+// looked up from the value of another attribute, e.g. tier from criticality.
+// Values without a mapping leave the attribute unset
+func NewMappingComputedAttribute(attr *attribute.Attribute, sourceAttribute attribute.AttributeName, mapping map[string]any) (*ComputedAttribute, error) {
+	if attr == nil {
+		return nil, fmt.Errorf("NewMappingComputedAttribute: attribute is nil")
+	}
+	coercedMapping := make(map[string]any, len(mapping))
+	for key, value := range mapping {
+		coerced, err := attr.CoerceValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("NewMappingComputedAttribute: Invalid value for %s: %s", key, err)
+		}
+		coercedMapping[key] = coerced
+	}
+	return &ComputedAttribute{
+		Attribute: attr,
+		DependsOn: []attribute.AttributeName{sourceAttribute},
+		Compute: func(entity *metadata.Entity) (any, error) {
+			sourceInstance := entity.GetAttributeByName(sourceAttribute)
+			if sourceInstance == nil {
+				return nil, nil
+			}
+			if value, ok := coercedMapping[fmt.Sprint(sourceInstance.Value)]; ok {
+				return value, nil
+			}
+			return nil, nil
+		},
+	}, nil
+}
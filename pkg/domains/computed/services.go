@@ -0,0 +1,198 @@
+package computed
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+)
+
+type ComputedAttributeService struct {
+	computedAttributes map[attribute.AttributeName]*ComputedAttribute
+	registrationOrder  []attribute.AttributeName
+	evaluationOrder    []*ComputedAttribute
+}
+
+func NewComputedAttributeService() (*ComputedAttributeService, error) {
+	return &ComputedAttributeService{
+		computedAttributes: map[attribute.AttributeName]*ComputedAttribute{},
+	}, nil
+}
+
+// RegisterComputedAttribute Register a computed attribute.
+// An error is returned if the attribute introduces a dependency cycle
+func (c *ComputedAttributeService) RegisterComputedAttribute(computedAttribute *ComputedAttribute) error {
+	if computedAttribute == nil {
+		return fmt.Errorf("RegisterComputedAttribute: computedAttribute is nil")
+	}
+	if computedAttribute.Attribute == nil || computedAttribute.Attribute.Name == "" {
+		return fmt.Errorf("RegisterComputedAttribute: Attribute is not set")
+	}
+	if computedAttribute.Compute == nil {
+		return fmt.Errorf("RegisterComputedAttribute: Compute function is not set for %s", computedAttribute.Attribute.Name)
+	}
+	name := computedAttribute.Attribute.Name
+	if _, ok := c.computedAttributes[name]; ok {
+		return fmt.Errorf("RegisterComputedAttribute: Computed attribute %s already registered", name)
+	}
+
+	c.computedAttributes[name] = computedAttribute
+	c.registrationOrder = append(c.registrationOrder, name)
+	evaluationOrder, err := c.getEvaluationOrder()
+	if err != nil {
+		delete(c.computedAttributes, name)
+		c.registrationOrder = c.registrationOrder[:len(c.registrationOrder)-1]
+		return fmt.Errorf("RegisterComputedAttribute: %s", err)
+	}
+	c.evaluationOrder = evaluationOrder
+	return nil
+}
+
+// getEvaluationOrder Order computed attributes so that each is evaluated
+// after the computed attributes it depends on
+func (c *ComputedAttributeService) getEvaluationOrder() ([]*ComputedAttribute, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[attribute.AttributeName]int{}
+	order := []*ComputedAttribute{}
+	var path []attribute.AttributeName
+
+	var visit func(name attribute.AttributeName) error
+	visit = func(name attribute.AttributeName) error {
+		computedAttribute, ok := c.computedAttributes[name]
+		if !ok {
+			// Dependency is provided by sources
+			return nil
+		}
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			cycle := append(path[slices.Index(path, name):], name)
+			parts := make([]string, 0, len(cycle))
+			for _, part := range cycle {
+				parts = append(parts, string(part))
+			}
+			return fmt.Errorf("Dependency cycle between computed attributes: %s", strings.Join(parts, " -> "))
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dependency := range computedAttribute.DependsOn {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		order = append(order, computedAttribute)
+		return nil
+	}
+
+	for _, name := range c.registrationOrder {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// GetAttributes Returns the attributes of all computed attributes
+func (c *ComputedAttributeService) GetAttributes() []attribute.Attribute {
+	attributes := []attribute.Attribute{}
+	for _, name := range c.registrationOrder {
+		attributes = append(attributes, *c.computedAttributes[name].Attribute)
+	}
+	return attributes
+}
+
+func (c *ComputedAttribute) appliesTo(entity *metadata.Entity) bool {
+	return len(c.EntityTypes) == 0 || slices.Contains(c.EntityTypes, entity.GetType())
+}
+
+// EvaluateEntity Compute all computed attributes for an entity.
+// An attribute that fails to compute does not prevent others from being
+// computed, except for those that depend on it, which are skipped.
+// Errors for all failed attributes are returned together
+func (c *ComputedAttributeService) EvaluateEntity(entity *metadata.Entity) error {
+	if entity == nil {
+		return fmt.Errorf("EvaluateEntity: entity is nil")
+	}
+	return errors.Join(c.evaluateEntity(entity)...)
+}
+
+// evaluateEntity Compute all computed attributes for an entity,
+// returning an error for each attribute that failed
+func (c *ComputedAttributeService) evaluateEntity(entity *metadata.Entity) []error {
+	var errs []error
+	failed := map[attribute.AttributeName]bool{}
+	for _, computedAttribute := range c.evaluationOrder {
+		// Dependencies are evaluated first, so failures propagate
+		// to attributes that depend on them transitively
+		if slices.ContainsFunc(computedAttribute.DependsOn, func(dependency attribute.AttributeName) bool {
+			return failed[dependency]
+		}) {
+			failed[computedAttribute.Attribute.Name] = true
+			continue
+		}
+		if err := computedAttribute.evaluate(entity); err != nil {
+			failed[computedAttribute.Attribute.Name] = true
+			errs = append(errs, fmt.Errorf("Error computing attribute %s for %s (%s): %s", computedAttribute.Attribute.Name, entity.GetName(), entity.GetType(), err))
+		}
+	}
+	return errs
+}
+
+func (c *ComputedAttribute) evaluate(entity *metadata.Entity) error {
+	if !c.appliesTo(entity) {
+		return nil
+	}
+	existing := entity.GetAttributeByName(c.Attribute.Name)
+	if existing != nil && !existing.IsEmpty() && !c.Override {
+		return nil
+	}
+	for _, dependency := range c.DependsOn {
+		if dependencyInstance := entity.GetAttributeByName(dependency); dependencyInstance == nil || dependencyInstance.IsEmpty() {
+			return nil
+		}
+	}
+
+	value, err := c.Compute(entity)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		return nil
+	}
+	entity.RemoveAttribute(c.Attribute.Name)
+	if err := entity.SetAttributeWithProvenance(c.Attribute, value, attribute.Provenance{
+		Source: ComputedAttributeSource,
+	}); err != nil {
+		// Keep the value discovered from sources
+		if existing != nil {
+			_ = entity.SetAttributeInstance(*existing)
+		}
+		return err
+	}
+	return nil
+}
+
+// Evaluate Compute all computed attributes for each entity.
+// Errors for individual attributes are returned together, after
+// all entities have been evaluated
+func (c *ComputedAttributeService) Evaluate(entities []*metadata.Entity) []error {
+	var errs []error
+	for _, entity := range entities {
+		if entity == nil {
+			errs = append(errs, fmt.Errorf("Evaluate: entity is nil"))
+			continue
+		}
+		errs = append(errs, c.evaluateEntity(entity)...)
+	}
+	return errs
+}
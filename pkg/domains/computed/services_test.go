@@ -0,0 +1,258 @@
+package computed
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+)
+
+var (
+	testAttributeDomain    = attribute.Attribute{Name: "domain", Type: reflect.TypeOf("")}
+	testAttributeFqdn      = attribute.Attribute{Name: "fqdn", Type: reflect.TypeOf("")}
+	testAttributeUrl       = attribute.Attribute{Name: "url", Type: reflect.TypeOf("")}
+	testAttributeOwner     = attribute.Attribute{Name: "owner", Type: reflect.TypeOf("")}
+	testAttributeRunbook   = attribute.Attribute{Name: "runbook", Type: reflect.TypeOf("")}
+	testAttributeDashboard = attribute.Attribute{Name: "dashboard", Type: reflect.TypeOf("")}
+)
+
+// newTestComputedAttribute Computed attribute that records the
+// order in which attributes are computed
+func newTestComputedAttribute(attr *attribute.Attribute, order *[]attribute.AttributeName, dependsOn ...attribute.AttributeName) *ComputedAttribute {
+	return &ComputedAttribute{
+		Attribute: attr,
+		DependsOn: dependsOn,
+		Compute: func(entity *metadata.Entity) (any, error) {
+			*order = append(*order, attr.Name)
+			return string(attr.Name), nil
+		},
+	}
+}
+
+func newTestEntity(t *testing.T, entityType metadata.EntityType, attributes map[*attribute.Attribute]any) *metadata.Entity {
+	t.Helper()
+	entity, err := metadata.NewEntity("web-01", entityType, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for attr, value := range attributes {
+		if err := entity.SetAttribute(attr, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return entity
+}
+
+func getAttributeValue(entity *metadata.Entity, name attribute.AttributeName) any {
+	if attributeInstance := entity.GetAttributeByName(name); attributeInstance != nil {
+		return attributeInstance.Value
+	}
+	return nil
+}
+
+func TestEvaluationOrder(t *testing.T) {
+	order := []attribute.AttributeName{}
+	service, err := NewComputedAttributeService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Registered before the attributes they depend on
+	for _, computedAttribute := range []*ComputedAttribute{
+		newTestComputedAttribute(&testAttributeUrl, &order, "fqdn"),
+		newTestComputedAttribute(&testAttributeDashboard, &order, "url", "fqdn"),
+		newTestComputedAttribute(&testAttributeFqdn, &order, "domain"),
+	} {
+		if err := service.RegisterComputedAttribute(computedAttribute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entity := newTestEntity(t, "server", map[*attribute.Attribute]any{&testAttributeDomain: "example.com"})
+	if err := service.EvaluateEntity(entity); err != nil {
+		t.Fatal(err)
+	}
+	expected := []attribute.AttributeName{"fqdn", "url", "dashboard"}
+	if !slices.Equal(order, expected) {
+		t.Errorf("Expected order %v, got %v", expected, order)
+	}
+	if provenance := entity.GetAttributeByName("dashboard").Provenance; provenance.Source != ComputedAttributeSource {
+		t.Errorf("Expected provenance %s, got %s", ComputedAttributeSource, provenance.Source)
+	}
+}
+
+func TestRegisterComputedAttributeCycle(t *testing.T) {
+	order := []attribute.AttributeName{}
+	tests := []struct {
+		name       string
+		attributes []*ComputedAttribute
+		err        string
+	}{
+		{
+			name: "self",
+			attributes: []*ComputedAttribute{
+				newTestComputedAttribute(&testAttributeFqdn, &order, "fqdn"),
+			},
+			err: "RegisterComputedAttribute: Dependency cycle between computed attributes: fqdn -> fqdn",
+		},
+		{
+			name: "indirect",
+			attributes: []*ComputedAttribute{
+				newTestComputedAttribute(&testAttributeFqdn, &order, "dashboard"),
+				newTestComputedAttribute(&testAttributeUrl, &order, "fqdn"),
+				newTestComputedAttribute(&testAttributeDashboard, &order, "url"),
+			},
+			err: "RegisterComputedAttribute: Dependency cycle between computed attributes: fqdn -> dashboard -> url -> fqdn",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, err := NewComputedAttributeService()
+			if err != nil {
+				t.Fatal(err)
+			}
+			last := len(test.attributes) - 1
+			for _, computedAttribute := range test.attributes[:last] {
+				if err := service.RegisterComputedAttribute(computedAttribute); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err = service.RegisterComputedAttribute(test.attributes[last])
+			if err == nil || err.Error() != test.err {
+				t.Errorf("Expected error %q, got %v", test.err, err)
+			}
+			// The attribute introducing the cycle is not registered
+			if attributes := service.GetAttributes(); len(attributes) != last {
+				t.Errorf("Expected %d attributes, got %d", last, len(attributes))
+			}
+		})
+	}
+}
+
+func TestEvaluateEntity(t *testing.T) {
+	tests := []struct {
+		name        string
+		override    bool
+		entityTypes []metadata.EntityType
+		entityType  metadata.EntityType
+		attributes  map[*attribute.Attribute]any
+		expected    any
+	}{
+		{
+			name:       "computed",
+			entityType: "server",
+			attributes: map[*attribute.Attribute]any{&testAttributeDomain: "example.com"},
+			expected:   "web-01.example.com",
+		},
+		{
+			name:       "missing dependency",
+			entityType: "server",
+			expected:   nil,
+		},
+		{
+			name:       "value from source is kept",
+			entityType: "server",
+			attributes: map[*attribute.Attribute]any{&testAttributeDomain: "example.com", &testAttributeFqdn: "web.example.org"},
+			expected:   "web.example.org",
+		},
+		{
+			name:       "empty value from source is replaced",
+			entityType: "server",
+			attributes: map[*attribute.Attribute]any{&testAttributeDomain: "example.com", &testAttributeFqdn: ""},
+			expected:   "web-01.example.com",
+		},
+		{
+			name:       "override replaces value from source",
+			override:   true,
+			entityType: "server",
+			attributes: map[*attribute.Attribute]any{&testAttributeDomain: "example.com", &testAttributeFqdn: "web.example.org"},
+			expected:   "web-01.example.com",
+		},
+		{
+			name:        "applies to entity type",
+			entityTypes: []metadata.EntityType{"server", "vm"},
+			entityType:  "server",
+			attributes:  map[*attribute.Attribute]any{&testAttributeDomain: "example.com"},
+			expected:    "web-01.example.com",
+		},
+		{
+			name:        "does not apply to other entity types",
+			entityTypes: []metadata.EntityType{"vm"},
+			entityType:  "server",
+			attributes:  map[*attribute.Attribute]any{&testAttributeDomain: "example.com"},
+			expected:    nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			computedAttribute, err := NewTemplateComputedAttribute(&testAttributeFqdn, `{{.Name}}.{{.Get "domain"}}`, "domain")
+			if err != nil {
+				t.Fatal(err)
+			}
+			computedAttribute.Override = test.override
+			computedAttribute.EntityTypes = test.entityTypes
+			service, err := NewComputedAttributeService()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := service.RegisterComputedAttribute(computedAttribute); err != nil {
+				t.Fatal(err)
+			}
+			entity := newTestEntity(t, test.entityType, test.attributes)
+			if err := service.EvaluateEntity(entity); err != nil {
+				t.Fatal(err)
+			}
+			if value := getAttributeValue(entity, "fqdn"); value != test.expected {
+				t.Errorf("Expected %#v, got %#v", test.expected, value)
+			}
+		})
+	}
+}
+
+func TestEvaluateEntityErrors(t *testing.T) {
+	order := []attribute.AttributeName{}
+	failing := &ComputedAttribute{
+		Attribute: &testAttributeFqdn,
+		DependsOn: []attribute.AttributeName{"domain"},
+		Compute: func(entity *metadata.Entity) (any, error) {
+			return nil, fmt.Errorf("Lookup failed")
+		},
+	}
+	service, err := NewComputedAttributeService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, computedAttribute := range []*ComputedAttribute{
+		failing,
+		newTestComputedAttribute(&testAttributeUrl, &order, "fqdn"),
+		newTestComputedAttribute(&testAttributeDashboard, &order, "url"),
+		newTestComputedAttribute(&testAttributeOwner, &order),
+		newTestComputedAttribute(&testAttributeRunbook, &order, "owner"),
+	} {
+		if err := service.RegisterComputedAttribute(computedAttribute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entity := newTestEntity(t, "server", map[*attribute.Attribute]any{&testAttributeDomain: "example.com"})
+	err = service.EvaluateEntity(entity)
+	expectedErr := "Error computing attribute fqdn for web-01 (server): Lookup failed"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Expected error %q, got %v", expectedErr, err)
+	}
+	// Attributes depending on the failed attribute are skipped, others are computed
+	expected := []attribute.AttributeName{"owner", "runbook"}
+	if !slices.Equal(order, expected) {
+		t.Errorf("Expected computed attributes %v, got %v", expected, order)
+	}
+
+	entities := []*metadata.Entity{
+		newTestEntity(t, "server", map[*attribute.Attribute]any{&testAttributeDomain: "example.com"}),
+		newTestEntity(t, "server", map[*attribute.Attribute]any{&testAttributeDomain: "example.org"}),
+	}
+	errs := service.Evaluate(entities)
+	if len(errs) != 2 || !strings.Contains(errs[1].Error(), "Lookup failed") {
+		t.Errorf("Expected an error for each entity, got %v", errs)
+	}
+}
@@ -0,0 +1,99 @@
+package dependencygraph
+
+import (
+	"fmt"
+	"strings"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+)
+
+// RestoreWave Entities that can be restored in parallel, once
+// all entities in previous waves have been restored
+type RestoreWave struct {
+	// Number Position of the wave in the plan, starting at 1
+	Number   int
+	Entities []*metadata.Entity
+}
+
+// RestorePlan Order in which entities should be restored,
+// such that each entity is restored after its dependencies
+type RestorePlan struct {
+	Waves []RestoreWave
+	// DroppedDependencies Relationships that were not taken into account,
+	// so entities may be placed in a wave before their dependencies
+	DroppedDependencies []DroppedDependency
+}
+
+func (p *RestorePlan) String() string {
+	var b strings.Builder
+	for _, wave := range p.Waves {
+		ids := make([]string, 0, len(wave.Entities))
+		for _, entity := range wave.Entities {
+			ids = append(ids, entity.GetId().String())
+		}
+		fmt.Fprintf(&b, "Wave %d: %s\n", wave.Number, strings.Join(ids, ", "))
+	}
+	for _, dropped := range p.DroppedDependencies {
+		fmt.Fprintf(&b, "Ignored: %s\n", dropped)
+	}
+	return b.String()
+}
+
+// DroppedReason Reason that a relationship is not included in a graph
+type DroppedReason string
+
+const (
+	// DroppedReasonNotFound The target is not one of the entities
+	DroppedReasonNotFound DroppedReason = "does not exist"
+	// DroppedReasonDecommissioned The target is decommissioned
+	DroppedReasonDecommissioned DroppedReason = "is decommissioned"
+	// DroppedReasonNoType The target has no type, as it could not be
+	// resolved to an entity. See EntityCollection.ResolveRelationships
+	DroppedReasonNoType DroppedReason = "has no type"
+)
+
+// DroppedDependency A relationship of an entity in the graph that is not
+// included in the graph, as its target is not an entity in the graph
+type DroppedDependency struct {
+	EntityId     metadata.EntityId
+	Relationship metadata.Relationship
+	Reason       DroppedReason
+}
+
+func (d DroppedDependency) String() string {
+	message := fmt.Sprintf("%s: %s relationship to %s, which %s", d.EntityId, d.Relationship.Type, d.Relationship.Target, d.Reason)
+	if !d.Relationship.Provenance.IsZero() {
+		message = fmt.Sprintf("%s (from %s)", message, d.Relationship.Provenance)
+	}
+	return message
+}
+
+// Cycle Entities that depend on each other, in dependency order.
+// The first entity depends on the second, and so on, and the
+// last entity depends on the first
+type Cycle []metadata.EntityId
+
+func (c Cycle) String() string {
+	parts := make([]string, 0, len(c)+1)
+	for _, id := range c {
+		parts = append(parts, id.String())
+	}
+	if len(c) > 0 {
+		parts = append(parts, c[0].String())
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// CycleError Returned when a restore order cannot be determined
+// because of dependency cycles
+type CycleError struct {
+	Cycles []Cycle
+}
+
+func (e *CycleError) Error() string {
+	cycles := make([]string, 0, len(e.Cycles))
+	for _, cycle := range e.Cycles {
+		cycles = append(cycles, cycle.String())
+	}
+	return fmt.Sprintf("Dependency cycles prevent determining restore order: %s", strings.Join(cycles, "; "))
+}
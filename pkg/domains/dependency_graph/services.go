@@ -0,0 +1,269 @@
+package dependencygraph
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+)
+
+// DependencyGraph Graph of entities and the entities they depend on,
+// using both host and dependency relationships.
+// Decommissioned entities, and relationships to entities that
+// are not in the graph, are not included. The relationships that
+// are not included are returned by GetDroppedDependencies
+type DependencyGraph struct {
+	entities map[metadata.EntityId]*metadata.Entity
+	// ids Entity ids, sorted by type and name
+	ids []metadata.EntityId
+	// dependencies Entities that each entity depends on, sorted by type and name
+	dependencies map[metadata.EntityId][]metadata.EntityId
+	// dropped Relationships to entities that are not in the graph
+	dropped []DroppedDependency
+}
+
+func compareEntityIds(a metadata.EntityId, b metadata.EntityId) int {
+	return cmp.Or(cmp.Compare(a.Type, b.Type), cmp.Compare(a.Name, b.Name))
+}
+
+func NewDependencyGraph(entities []*metadata.Entity) (*DependencyGraph, error) {
+	graph := &DependencyGraph{
+		entities:     map[metadata.EntityId]*metadata.Entity{},
+		ids:          []metadata.EntityId{},
+		dependencies: map[metadata.EntityId][]metadata.EntityId{},
+		dropped:      []DroppedDependency{},
+	}
+	decommissioned := map[metadata.EntityId]bool{}
+	for _, entity := range entities {
+		if entity == nil {
+			return nil, fmt.Errorf("NewDependencyGraph: entity is nil")
+		}
+		if entity.IsDecommissioned() {
+			decommissioned[entity.GetId()] = true
+			continue
+		}
+		if _, ok := graph.entities[entity.GetId()]; ok {
+			return nil, fmt.Errorf("NewDependencyGraph: Duplicate entity %s", entity.GetId())
+		}
+		graph.entities[entity.GetId()] = entity
+		graph.ids = append(graph.ids, entity.GetId())
+	}
+	slices.SortFunc(graph.ids, compareEntityIds)
+
+	for _, id := range graph.ids {
+		dependencies := []metadata.EntityId{}
+		for _, entityRelationship := range graph.entities[id].GetRelationships() {
+			if _, ok := graph.entities[entityRelationship.Target]; !ok {
+				dropped := DroppedDependency{EntityId: id, Relationship: entityRelationship, Reason: DroppedReasonNotFound}
+				if entityRelationship.Target.Type == "" {
+					dropped.Reason = DroppedReasonNoType
+				} else if decommissioned[entityRelationship.Target] {
+					dropped.Reason = DroppedReasonDecommissioned
+				}
+				graph.dropped = append(graph.dropped, dropped)
+				continue
+			}
+			if !slices.Contains(dependencies, entityRelationship.Target) {
+				dependencies = append(dependencies, entityRelationship.Target)
+			}
+		}
+		slices.SortFunc(dependencies, compareEntityIds)
+		graph.dependencies[id] = dependencies
+	}
+	return graph, nil
+}
+
+// GetEntity Returns the entity in the graph with the given id
+func (g *DependencyGraph) GetEntity(id metadata.EntityId) *metadata.Entity {
+	return g.entities[id]
+}
+
+// GetDependencies Returns the ids of the entities that an entity depends on
+func (g *DependencyGraph) GetDependencies(id metadata.EntityId) []metadata.EntityId {
+	return g.dependencies[id]
+}
+
+// GetDroppedDependencies Returns the relationships of entities in the graph
+// whose target is not in the graph, sorted by entity type and name
+func (g *DependencyGraph) GetDroppedDependencies() []DroppedDependency {
+	return slices.Clone(g.dropped)
+}
+
+// GetRestorePlan Group entities into waves, using a topological sort of
+// the graph. Each entity is placed in the first wave after all of its
+// dependencies. Entities within a wave are sorted by type and name.
+// The relationships that are not included in the graph are returned with
+// the plan. Returns a CycleError if entities depend on each other
+func (g *DependencyGraph) GetRestorePlan() (*RestorePlan, error) {
+	remaining := map[metadata.EntityId]int{}
+	dependents := map[metadata.EntityId][]metadata.EntityId{}
+	for _, id := range g.ids {
+		remaining[id] = len(g.dependencies[id])
+		for _, dependency := range g.dependencies[id] {
+			dependents[dependency] = append(dependents[dependency], id)
+		}
+	}
+
+	plan := &RestorePlan{Waves: []RestoreWave{}, DroppedDependencies: g.GetDroppedDependencies()}
+	var ready []metadata.EntityId
+	for _, id := range g.ids {
+		if remaining[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+	restored := 0
+	for len(ready) > 0 {
+		slices.SortFunc(ready, compareEntityIds)
+		wave := RestoreWave{Number: len(plan.Waves) + 1}
+		var next []metadata.EntityId
+		for _, id := range ready {
+			wave.Entities = append(wave.Entities, g.entities[id])
+			for _, dependent := range dependents[id] {
+				remaining[dependent]--
+				if remaining[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		restored += len(ready)
+		plan.Waves = append(plan.Waves, wave)
+		ready = next
+	}
+
+	if restored < len(g.ids) {
+		return nil, &CycleError{Cycles: g.GetCycles()}
+	}
+	return plan, nil
+}
+
+// MaxCycles Maximum number of cycles returned by GetCycles. The number
+// of cycles can grow exponentially with the number of entities that
+// depend on each other
+const MaxCycles int = 1000
+
+// GetCycles Returns every elementary cycle in the graph, up to MaxCycles,
+// in a deterministic order. Each cycle starts at its first entity by type
+// and name. Uses Johnson's algorithm
+func (g *DependencyGraph) GetCycles() []Cycle {
+	components := map[metadata.EntityId]int{}
+	for i, component := range g.getStronglyConnectedComponents() {
+		for _, id := range component {
+			components[id] = i
+		}
+	}
+	position := map[metadata.EntityId]int{}
+	for i, id := range g.ids {
+		position[id] = i
+	}
+
+	cycles := []Cycle{}
+	for _, start := range g.ids {
+		// Cycles through earlier entities have already been found
+		isCandidate := func(id metadata.EntityId) bool {
+			return components[id] == components[start] && position[id] >= position[start]
+		}
+		blocked := map[metadata.EntityId]bool{}
+		blockedBy := map[metadata.EntityId][]metadata.EntityId{}
+		var unblock func(id metadata.EntityId)
+		unblock = func(id metadata.EntityId) {
+			blocked[id] = false
+			for _, other := range blockedBy[id] {
+				if blocked[other] {
+					unblock(other)
+				}
+			}
+			blockedBy[id] = nil
+		}
+		var path []metadata.EntityId
+		var circuit func(id metadata.EntityId) bool
+		circuit = func(id metadata.EntityId) bool {
+			found := false
+			path = append(path, id)
+			blocked[id] = true
+			for _, dependency := range g.dependencies[id] {
+				if len(cycles) >= MaxCycles {
+					break
+				}
+				if !isCandidate(dependency) {
+					continue
+				}
+				if dependency == start {
+					cycles = append(cycles, Cycle(slices.Clone(path)))
+					found = true
+				} else if !blocked[dependency] && circuit(dependency) {
+					found = true
+				}
+			}
+			if found {
+				unblock(id)
+			} else {
+				for _, dependency := range g.dependencies[id] {
+					if isCandidate(dependency) && !slices.Contains(blockedBy[dependency], id) {
+						blockedBy[dependency] = append(blockedBy[dependency], id)
+					}
+				}
+			}
+			path = path[:len(path)-1]
+			return found
+		}
+		circuit(start)
+		if len(cycles) >= MaxCycles {
+			break
+		}
+	}
+	return cycles
+}
+
+// getStronglyConnectedComponents Tarjan's algorithm. Members of each
+// component are sorted, and components are sorted by their first member
+func (g *DependencyGraph) getStronglyConnectedComponents() [][]metadata.EntityId {
+	index := 0
+	indices := map[metadata.EntityId]int{}
+	lowLinks := map[metadata.EntityId]int{}
+	onStack := map[metadata.EntityId]bool{}
+	var stack []metadata.EntityId
+	var components [][]metadata.EntityId
+
+	var visit func(id metadata.EntityId)
+	visit = func(id metadata.EntityId) {
+		indices[id] = index
+		lowLinks[id] = index
+		index++
+		stack = append(stack, id)
+		onStack[id] = true
+
+		for _, dependency := range g.dependencies[id] {
+			if _, visited := indices[dependency]; !visited {
+				visit(dependency)
+				lowLinks[id] = min(lowLinks[id], lowLinks[dependency])
+			} else if onStack[dependency] {
+				lowLinks[id] = min(lowLinks[id], indices[dependency])
+			}
+		}
+
+		if lowLinks[id] == indices[id] {
+			var component []metadata.EntityId
+			for {
+				member := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[member] = false
+				component = append(component, member)
+				if member == id {
+					break
+				}
+			}
+			slices.SortFunc(component, compareEntityIds)
+			components = append(components, component)
+		}
+	}
+	for _, id := range g.ids {
+		if _, visited := indices[id]; !visited {
+			visit(id)
+		}
+	}
+	slices.SortFunc(components, func(a, b []metadata.EntityId) int {
+		return compareEntityIds(a[0], b[0])
+	})
+	return components
+}
@@ -0,0 +1,295 @@
+package dependencygraph
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/relationship"
+)
+
+// testEntity Definition of an entity, with the entities it depends on
+// in the form type/name
+type testEntity struct {
+	id             string
+	dependsOn      []string
+	hostedOn       string
+	decommissioned bool
+}
+
+func newTestEntities(t *testing.T, definitions []testEntity) []*metadata.Entity {
+	t.Helper()
+	entities := []*metadata.Entity{}
+	for _, definition := range definitions {
+		id := metadata.ParseEntityId(definition.id)
+		entity, err := metadata.NewEntity(id.Name, id.Type, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, target := range definition.dependsOn {
+			entity.MergeRelationship(metadata.Relationship{Type: relationship.RelationshipTypeNormal, Target: metadata.ParseEntityId(target)})
+		}
+		if definition.hostedOn != "" {
+			entity.MergeRelationship(metadata.Relationship{Type: relationship.RelationshipTypeHost, Target: metadata.ParseEntityId(definition.hostedOn)})
+		}
+		if definition.decommissioned {
+			if err := entity.SetLifecycle(metadata.LifecycleDecommissioned); err != nil {
+				t.Fatal(err)
+			}
+		}
+		entities = append(entities, entity)
+	}
+	return entities
+}
+
+func getWaveIds(plan *RestorePlan) [][]string {
+	waves := [][]string{}
+	for _, wave := range plan.Waves {
+		ids := []string{}
+		for _, entity := range wave.Entities {
+			ids = append(ids, entity.GetId().String())
+		}
+		waves = append(waves, ids)
+	}
+	return waves
+}
+
+func TestGetRestorePlan(t *testing.T) {
+	tests := []struct {
+		name     string
+		entities []testEntity
+		waves    [][]string
+		dropped  []string
+	}{
+		{
+			name:  "empty",
+			waves: [][]string{},
+		},
+		{
+			name: "independent entities share a wave, sorted by type and name",
+			entities: []testEntity{
+				{id: "service/b"},
+				{id: "server/z"},
+				{id: "service/a"},
+			},
+			waves: [][]string{{"server/z", "service/a", "service/b"}},
+		},
+		{
+			name: "chain",
+			entities: []testEntity{
+				{id: "service/web", dependsOn: []string{"service/api"}},
+				{id: "service/api", dependsOn: []string{"service/db"}},
+				{id: "service/db"},
+			},
+			waves: [][]string{{"service/db"}, {"service/api"}, {"service/web"}},
+		},
+		{
+			name: "hosts are restored before hosted entities",
+			entities: []testEntity{
+				{id: "service/api", hostedOn: "server/web-01"},
+				{id: "server/web-01"},
+			},
+			waves: [][]string{{"server/web-01"}, {"service/api"}},
+		},
+		{
+			name: "entity is placed after its latest dependency",
+			entities: []testEntity{
+				{id: "service/a"},
+				{id: "service/b", dependsOn: []string{"service/a"}},
+				{id: "service/c", dependsOn: []string{"service/a", "service/b"}},
+				{id: "service/d", dependsOn: []string{"service/a"}},
+			},
+			waves: [][]string{{"service/a"}, {"service/b", "service/d"}, {"service/c"}},
+		},
+		{
+			name: "dropped dependencies are reported",
+			entities: []testEntity{
+				{id: "service/api", dependsOn: []string{"service/cache", "service/old", "dns"}},
+				{id: "service/old", decommissioned: true},
+			},
+			waves: [][]string{{"service/api"}},
+			dropped: []string{
+				"service/api: Normal relationship to service/cache, which does not exist",
+				"service/api: Normal relationship to service/old, which is decommissioned",
+				"service/api: Normal relationship to dns, which has no type",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			graph, err := NewDependencyGraph(newTestEntities(t, test.entities))
+			if err != nil {
+				t.Fatal(err)
+			}
+			plan, err := graph.GetRestorePlan()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if waves := getWaveIds(plan); !slices.EqualFunc(waves, test.waves, slices.Equal) {
+				t.Errorf("Expected waves %v, got %v", test.waves, waves)
+			}
+			dropped := []string{}
+			for _, dependency := range plan.DroppedDependencies {
+				dropped = append(dropped, dependency.String())
+			}
+			slices.Sort(dropped)
+			expected := slices.Sorted(slices.Values(test.dropped))
+			if !slices.Equal(dropped, expected) {
+				t.Errorf("Expected dropped dependencies %v, got %v", expected, dropped)
+			}
+		})
+	}
+}
+
+func TestGetCycles(t *testing.T) {
+	tests := []struct {
+		name     string
+		entities []testEntity
+		cycles   []string
+	}{
+		{
+			name: "no cycles",
+			entities: []testEntity{
+				{id: "service/a", dependsOn: []string{"service/b"}},
+				{id: "service/b"},
+			},
+		},
+		{
+			name: "two entities",
+			entities: []testEntity{
+				{id: "service/a", dependsOn: []string{"service/b"}},
+				{id: "service/b", dependsOn: []string{"service/a"}},
+			},
+			cycles: []string{"service/a -> service/b -> service/a"},
+		},
+		{
+			name: "cycle through a host",
+			entities: []testEntity{
+				{id: "server/vm", dependsOn: []string{"service/storage"}},
+				{id: "service/storage", hostedOn: "server/vm"},
+			},
+			cycles: []string{"server/vm -> service/storage -> server/vm"},
+		},
+		{
+			name: "separate cycles are reported separately",
+			entities: []testEntity{
+				{id: "service/a", dependsOn: []string{"service/b"}},
+				{id: "service/b", dependsOn: []string{"service/a"}},
+				{id: "service/c", dependsOn: []string{"service/d", "service/a"}},
+				{id: "service/d", dependsOn: []string{"service/e"}},
+				{id: "service/e", dependsOn: []string{"service/c"}},
+			},
+			cycles: []string{
+				"service/a -> service/b -> service/a",
+				"service/c -> service/d -> service/e -> service/c",
+			},
+		},
+		{
+			name: "every cycle within a group of entities",
+			entities: []testEntity{
+				{id: "service/a", dependsOn: []string{"service/b"}},
+				{id: "service/b", dependsOn: []string{"service/a", "service/c"}},
+				{id: "service/c", dependsOn: []string{"service/a", "service/b"}},
+			},
+			cycles: []string{
+				"service/a -> service/b -> service/a",
+				"service/a -> service/b -> service/c -> service/a",
+				"service/b -> service/c -> service/b",
+			},
+		},
+		{
+			name: "entity depending on itself",
+			entities: []testEntity{
+				{id: "service/a", dependsOn: []string{"service/a"}},
+			},
+			cycles: []string{"service/a -> service/a"},
+		},
+		{
+			name: "decommissioned entities break cycles",
+			entities: []testEntity{
+				{id: "service/a", dependsOn: []string{"service/b"}},
+				{id: "service/b", dependsOn: []string{"service/a"}, decommissioned: true},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			graph, err := NewDependencyGraph(newTestEntities(t, test.entities))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cycles := []string{}
+			for _, cycle := range graph.GetCycles() {
+				cycles = append(cycles, cycle.String())
+			}
+			expected := append([]string{}, test.cycles...)
+			if !slices.Equal(cycles, expected) {
+				t.Errorf("Expected cycles %v, got %v", expected, cycles)
+			}
+
+			_, err = graph.GetRestorePlan()
+			var cycleErr *CycleError
+			if len(expected) == 0 && err != nil {
+				t.Errorf("Expected restore plan, got %s", err)
+			}
+			if len(expected) > 0 && (!errors.As(err, &cycleErr) || len(cycleErr.Cycles) != len(expected)) {
+				t.Errorf("Expected CycleError with %d cycles, got %v", len(expected), err)
+			}
+		})
+	}
+}
+
+// newCompleteGraph Entities that each depend on all of the others
+func newCompleteGraph(t *testing.T, size int) *DependencyGraph {
+	t.Helper()
+	definitions := []testEntity{}
+	for i := range size {
+		definition := testEntity{id: fmt.Sprintf("service/%d", i)}
+		for j := range size {
+			if i != j {
+				definition.dependsOn = append(definition.dependsOn, fmt.Sprintf("service/%d", j))
+			}
+		}
+		definitions = append(definitions, definition)
+	}
+	graph, err := NewDependencyGraph(newTestEntities(t, definitions))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return graph
+}
+
+func TestGetCyclesCount(t *testing.T) {
+	tests := []struct {
+		size     int
+		expected int
+	}{
+		{size: 2, expected: 1},
+		{size: 3, expected: 5},
+		{size: 4, expected: 20},
+		// 14280 cycles, limited to MaxCycles
+		{size: 8, expected: MaxCycles},
+	}
+	for _, test := range tests {
+		cycles := newCompleteGraph(t, test.size).GetCycles()
+		if len(cycles) != test.expected {
+			t.Errorf("Complete graph of %d entities: Expected %d cycles, got %d", test.size, test.expected, len(cycles))
+		}
+		seen := map[string]bool{}
+		for _, cycle := range cycles {
+			if seen[cycle.String()] {
+				t.Errorf("Complete graph of %d entities: Duplicate cycle %s", test.size, cycle)
+			}
+			seen[cycle.String()] = true
+		}
+	}
+}
+
+func TestNewDependencyGraphDuplicateEntity(t *testing.T) {
+	_, err := NewDependencyGraph(newTestEntities(t, []testEntity{{id: "service/a"}, {id: "service/a"}}))
+	if err == nil {
+		t.Error("Expected error for duplicate entity")
+	}
+}
@@ -0,0 +1,120 @@
+package discovery
+
+import (
+	"fmt"
+	"strings"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+)
+
+// NameNormalizer Transforms an entity name before it is compared
+// with the names of other entities
+type NameNormalizer func(name metadata.EntityName) metadata.EntityName
+
+// NormalizeCase Compare names case-insensitively
+func NormalizeCase(name metadata.EntityName) metadata.EntityName {
+	return metadata.EntityName(strings.ToLower(string(name)))
+}
+
+// NormalizeSeparators Treat underscores and hyphens as equivalent,
+// e.g. docker_host_01 and docker-host-01
+func NormalizeSeparators(name metadata.EntityName) metadata.EntityName {
+	return metadata.EntityName(strings.ReplaceAll(string(name), "_", "-"))
+}
+
+// StripDomain Compare names without their domain, e.g.
+// docker-host-01.example.com matches docker-host-01
+func StripDomain(name metadata.EntityName) metadata.EntityName {
+	host, _, _ := strings.Cut(string(name), ".")
+	return metadata.EntityName(host)
+}
+
+// IdentityRules Rules used to determine whether entities with
+// different names, provided by different sources, are the same entity.
+// Entities are only matched with entities of the same type
+type IdentityRules struct {
+	// NameNormalizers Applied in order to entity names and aliases
+	// before comparison
+	NameNormalizers []NameNormalizer
+	// MatchAttributes Attributes whose value uniquely identifies an
+	// entity, e.g. ip_address. Attributes are checked in order
+	MatchAttributes []attribute.AttributeName
+}
+
+// normalizeName Apply the name normalizers to a name
+func (r *IdentityRules) normalizeName(name metadata.EntityName) metadata.EntityName {
+	if r == nil {
+		return name
+	}
+	for _, normalizer := range r.NameNormalizers {
+		name = normalizer(name)
+	}
+	return name
+}
+
+// findMatchingEntity Find an entity in the collection that is the same
+// entity as the given entity, under a different name.
+// Entities are matched by alias and normalized name, then by the
+// identity rules' match attributes
+func (e *EntityCollection) findMatchingEntity(entity *metadata.Entity) (*metadata.Entity, error) {
+	var nameMatches []metadata.EntityId
+	for _, name := range entity.GetNames() {
+		for id := range e.index.getByName(name) {
+			if id.Type == entity.GetType() {
+				nameMatches = append(nameMatches, id)
+			}
+		}
+	}
+	if matches := e.getEntitiesInOrder(nameMatches); len(matches) > 0 {
+		return matches[0], nil
+	}
+	if e.identityRules == nil {
+		return nil, nil
+	}
+
+	for _, name := range e.identityRules.MatchAttributes {
+		attributeInstance := entity.GetAttributeByName(name)
+		if attributeInstance == nil || attributeInstance.IsEmpty() {
+			continue
+		}
+		var ids []metadata.EntityId
+		for id := range e.index.getByValue(name, attributeInstance.Value) {
+			if id.Type == entity.GetType() {
+				ids = append(ids, id)
+			}
+		}
+		matches := e.getEntitiesInOrder(ids)
+		switch len(matches) {
+		case 0:
+			continue
+		case 1:
+			return matches[0], nil
+		default:
+			names := make([]string, 0, len(matches))
+			for _, match := range matches {
+				names = append(names, string(match.GetName()))
+			}
+			return nil, fmt.Errorf("%s (%s) matches multiple entities by %s '%v': %s", entity.GetName(), entity.GetType(), name, attributeInstance.Value, strings.Join(names, ", "))
+		}
+	}
+	return nil, nil
+}
+
+// ResolveEntity Returns the entity with the given name or alias,
+// applying the collection's name normalization
+func (e *EntityCollection) ResolveEntity(name metadata.EntityName, entityType metadata.EntityType) *metadata.Entity {
+	if entity := e.GetEntityByNameAndType(name, entityType); entity != nil {
+		return entity
+	}
+	var ids []metadata.EntityId
+	for id := range e.index.getByName(name) {
+		if id.Type == entityType {
+			ids = append(ids, id)
+		}
+	}
+	if matches := e.getEntitiesInOrder(ids); len(matches) > 0 {
+		return matches[0]
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package discovery
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+)
+
+var testAttributeIpAddress = attribute.Attribute{Name: "ip_address", Type: reflect.TypeOf("")}
+
+// testIdentityEntity Definition of an entity to add to a collection
+type testIdentityEntity struct {
+	id        string
+	aliases   []metadata.EntityName
+	ipAddress string
+}
+
+func newTestIdentityEntity(t *testing.T, definition testIdentityEntity) *metadata.Entity {
+	t.Helper()
+	id := metadata.ParseEntityId(definition.id)
+	entity, err := metadata.NewEntity(id.Name, id.Type, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, alias := range definition.aliases {
+		entity.AddAlias(alias)
+	}
+	if definition.ipAddress != "" {
+		if err := entity.SetAttribute(&testAttributeIpAddress, definition.ipAddress); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return entity
+}
+
+func TestAddEntityIdentityMatching(t *testing.T) {
+	allNormalizers := []NameNormalizer{NormalizeCase, NormalizeSeparators, StripDomain}
+	tests := []struct {
+		name          string
+		identityRules *IdentityRules
+		entities      []testIdentityEntity
+		expected      []string
+		warnings      int
+	}{
+		{
+			name:     "same name and type",
+			entities: []testIdentityEntity{{id: "server/web-01"}, {id: "server/web-01"}},
+			expected: []string{"server/web-01"},
+		},
+		{
+			name:     "same name and different type",
+			entities: []testIdentityEntity{{id: "server/web-01"}, {id: "service/web-01"}},
+			expected: []string{"server/web-01", "service/web-01"},
+		},
+		{
+			name:     "name matches alias of existing entity",
+			entities: []testIdentityEntity{{id: "server/web-01", aliases: []metadata.EntityName{"i-0abc"}}, {id: "server/i-0abc"}},
+			expected: []string{"server/web-01"},
+		},
+		{
+			name:     "alias matches name of existing entity",
+			entities: []testIdentityEntity{{id: "server/i-0abc"}, {id: "server/web-01", aliases: []metadata.EntityName{"i-0abc"}}},
+			expected: []string{"server/i-0abc"},
+		},
+		{
+			name:     "names are not normalized without identity rules",
+			entities: []testIdentityEntity{{id: "server/web-01"}, {id: "server/WEB-01"}},
+			expected: []string{"server/web-01", "server/WEB-01"},
+		},
+		{
+			name:          "normalized case",
+			identityRules: &IdentityRules{NameNormalizers: []NameNormalizer{NormalizeCase}},
+			entities:      []testIdentityEntity{{id: "server/web-01"}, {id: "server/WEB-01"}},
+			expected:      []string{"server/web-01"},
+		},
+		{
+			name:          "all normalizers",
+			identityRules: &IdentityRules{NameNormalizers: allNormalizers},
+			entities:      []testIdentityEntity{{id: "server/docker-host-01"}, {id: "server/Docker_Host_01.example.com"}},
+			expected:      []string{"server/docker-host-01"},
+		},
+		{
+			name:          "normalized alias",
+			identityRules: &IdentityRules{NameNormalizers: allNormalizers},
+			entities:      []testIdentityEntity{{id: "server/web-01", aliases: []metadata.EntityName{"I-0ABC"}}, {id: "server/i-0abc"}},
+			expected:      []string{"server/web-01"},
+		},
+		{
+			name:          "match attribute",
+			identityRules: &IdentityRules{MatchAttributes: []attribute.AttributeName{"ip_address"}},
+			entities:      []testIdentityEntity{{id: "server/web-01", ipAddress: "10.0.0.1"}, {id: "server/i-0abc", ipAddress: "10.0.0.1"}},
+			expected:      []string{"server/web-01"},
+		},
+		{
+			name:          "match attribute with different value",
+			identityRules: &IdentityRules{MatchAttributes: []attribute.AttributeName{"ip_address"}},
+			entities:      []testIdentityEntity{{id: "server/web-01", ipAddress: "10.0.0.1"}, {id: "server/i-0abc", ipAddress: "10.0.0.2"}},
+			expected:      []string{"server/web-01", "server/i-0abc"},
+		},
+		{
+			name:          "match attribute only matches same type",
+			identityRules: &IdentityRules{MatchAttributes: []attribute.AttributeName{"ip_address"}},
+			entities:      []testIdentityEntity{{id: "server/web-01", ipAddress: "10.0.0.1"}, {id: "service/web", ipAddress: "10.0.0.1"}},
+			expected:      []string{"server/web-01", "service/web"},
+		},
+		{
+			name:          "match attribute without value",
+			identityRules: &IdentityRules{MatchAttributes: []attribute.AttributeName{"ip_address"}},
+			entities:      []testIdentityEntity{{id: "server/web-01"}, {id: "server/i-0abc"}},
+			expected:      []string{"server/web-01", "server/i-0abc"},
+		},
+		{
+			name:          "ambiguous match attribute",
+			identityRules: &IdentityRules{MatchAttributes: []attribute.AttributeName{"ip_address"}},
+			entities: []testIdentityEntity{
+				{id: "server/web-01", ipAddress: "10.0.0.1"},
+				{id: "server/web-02", aliases: []metadata.EntityName{"web"}},
+				{id: "server/web", ipAddress: "10.0.0.1"},
+				{id: "server/i-0abc", ipAddress: "10.0.0.1"},
+			},
+			expected: []string{"server/web-01", "server/web-02", "server/i-0abc"},
+			warnings: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			collection, err := NewEntityCollectionWithIdentityRules(test.identityRules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, definition := range test.entities {
+				if err := collection.AddEntity(newTestIdentityEntity(t, definition)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			ids := []string{}
+			for _, entity := range collection.GetEntities() {
+				ids = append(ids, entity.GetId().String())
+			}
+			if !slices.Equal(ids, test.expected) {
+				t.Errorf("Expected entities %v, got %v", test.expected, ids)
+			}
+			if warnings := len(collection.GetDiagnostics()); warnings != test.warnings {
+				t.Errorf("Expected %d warnings, got %d: %v", test.warnings, warnings, collection.GetDiagnostics())
+			}
+		})
+	}
+}
+
+func TestResolveEntity(t *testing.T) {
+	collection, err := NewEntityCollectionWithIdentityRules(&IdentityRules{NameNormalizers: []NameNormalizer{NormalizeCase}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, definition := range []testIdentityEntity{
+		{id: "server/web-01", aliases: []metadata.EntityName{"i-0abc"}},
+		{id: "service/web-01"},
+	} {
+		if err := collection.AddEntity(newTestIdentityEntity(t, definition)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := collection.RenameEntity(metadata.ParseEntityId("service/web-01"), "web"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		entityType metadata.EntityType
+		expected   string
+	}{
+		{name: "web-01", entityType: "server", expected: "server/web-01"},
+		{name: "I-0ABC", entityType: "server", expected: "server/web-01"},
+		{name: "i-0abc", entityType: "service"},
+		{name: "WEB-01", entityType: "service", expected: "service/web"},
+		{name: "web", entityType: "service", expected: "service/web"},
+		{name: "web-02", entityType: "server"},
+	}
+	for _, test := range tests {
+		entity := collection.ResolveEntity(metadata.EntityName(test.name), test.entityType)
+		resolved := ""
+		if entity != nil {
+			resolved = entity.GetId().String()
+		}
+		if resolved != test.expected {
+			t.Errorf("ResolveEntity(%s, %s): Expected %q, got %q", test.name, test.entityType, test.expected, resolved)
+		}
+	}
+}
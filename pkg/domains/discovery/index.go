@@ -0,0 +1,109 @@
+package discovery
+
+import (
+	"fmt"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+)
+
+// entityIndex Index of the entities in a collection by normalized name
+// and alias, and by the value of each of the identity rules' match
+// attributes, so that identities can be resolved without scanning
+// every entity
+type entityIndex struct {
+	identityRules *IdentityRules
+	names         map[metadata.EntityName]map[metadata.EntityId]bool
+	values        map[attribute.AttributeName]map[string]map[metadata.EntityId]bool
+	// entityNames Index keys of each entity, used to remove the entity
+	entityNames  map[metadata.EntityId][]metadata.EntityName
+	entityValues map[metadata.EntityId]map[attribute.AttributeName]string
+}
+
+func newEntityIndex(identityRules *IdentityRules) *entityIndex {
+	index := &entityIndex{identityRules: identityRules}
+	index.clear()
+	return index
+}
+
+func (i *entityIndex) clear() {
+	i.names = map[metadata.EntityName]map[metadata.EntityId]bool{}
+	i.values = map[attribute.AttributeName]map[string]map[metadata.EntityId]bool{}
+	i.entityNames = map[metadata.EntityId][]metadata.EntityName{}
+	i.entityValues = map[metadata.EntityId]map[attribute.AttributeName]string{}
+}
+
+// valueKey Returns a comparable key for an attribute value.
+// The Go syntax representation includes the type and orders map keys
+func valueKey(value any) string {
+	return fmt.Sprintf("%#v", value)
+}
+
+// add Index an entity under its current id, names and attribute values
+func (i *entityIndex) add(entity *metadata.Entity) {
+	id := entity.GetId()
+	for _, name := range entity.GetNames() {
+		normalized := i.identityRules.normalizeName(name)
+		if i.names[normalized] == nil {
+			i.names[normalized] = map[metadata.EntityId]bool{}
+		}
+		i.names[normalized][id] = true
+		i.entityNames[id] = append(i.entityNames[id], normalized)
+	}
+	if i.identityRules == nil {
+		return
+	}
+	for _, name := range i.identityRules.MatchAttributes {
+		attributeInstance := entity.GetAttributeByName(name)
+		if attributeInstance == nil || attributeInstance.IsEmpty() {
+			continue
+		}
+		key := valueKey(attributeInstance.Value)
+		if i.values[name] == nil {
+			i.values[name] = map[string]map[metadata.EntityId]bool{}
+		}
+		if i.values[name][key] == nil {
+			i.values[name][key] = map[metadata.EntityId]bool{}
+		}
+		i.values[name][key][id] = true
+		if i.entityValues[id] == nil {
+			i.entityValues[id] = map[attribute.AttributeName]string{}
+		}
+		i.entityValues[id][name] = key
+	}
+}
+
+// remove Remove an entity from the index
+func (i *entityIndex) remove(id metadata.EntityId) {
+	for _, name := range i.entityNames[id] {
+		delete(i.names[name], id)
+		if len(i.names[name]) == 0 {
+			delete(i.names, name)
+		}
+	}
+	for name, key := range i.entityValues[id] {
+		delete(i.values[name][key], id)
+		if len(i.values[name][key]) == 0 {
+			delete(i.values[name], key)
+		}
+	}
+	delete(i.entityNames, id)
+	delete(i.entityValues, id)
+}
+
+// update Re-index an entity whose names or attributes may have changed
+func (i *entityIndex) update(entity *metadata.Entity) {
+	i.remove(entity.GetId())
+	i.add(entity)
+}
+
+// getByName Returns the ids of entities of any type with
+// the name or alias, once normalized
+func (i *entityIndex) getByName(name metadata.EntityName) map[metadata.EntityId]bool {
+	return i.names[i.identityRules.normalizeName(name)]
+}
+
+// getByValue Returns the ids of entities with the value for a match attribute
+func (i *entityIndex) getByValue(name attribute.AttributeName, value any) map[metadata.EntityId]bool {
+	return i.values[name][valueKey(value)]
+}
@@ -0,0 +1,154 @@
+package discovery
+
+import (
+	"fmt"
+	"strings"
+
+	dependencygraph "gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/dependency_graph"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/relationship"
+)
+
+// findEntitiesByName Returns entities of any type with the given name
+// or alias, applying the collection's name normalization
+func (e *EntityCollection) findEntitiesByName(name metadata.EntityName) []*metadata.Entity {
+	var ids []metadata.EntityId
+	for id := range e.index.getByName(name) {
+		ids = append(ids, id)
+	}
+	return e.getEntitiesInOrder(ids)
+}
+
+// ResolveRelationshipTarget Returns the entity that a relationship target
+// refers to. Targets without a type are resolved by name, and only if the
+// name identifies a single entity
+func (e *EntityCollection) ResolveRelationshipTarget(target metadata.EntityId) (*metadata.Entity, error) {
+	if target.Type != "" {
+		return e.ResolveEntity(target.Name, target.Type), nil
+	}
+	matches := e.findEntitiesByName(target.Name)
+	if len(matches) > 1 {
+		ids := make([]string, 0, len(matches))
+		for _, match := range matches {
+			ids = append(ids, match.GetId().String())
+		}
+		return nil, fmt.Errorf("Relationship target %s is ambiguous: %s", target.Name, strings.Join(ids, ", "))
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	return nil, nil
+}
+
+// ResolveRelationships Replace relationship targets with the id of the
+// entity they refer to, resolving targets without a type and targets
+// that refer to an entity by alias. Targets that cannot be resolved
+// are left unchanged. Call again after changing the relationships of
+// entities in the collection, so that GetDependents reflects the changes
+func (e *EntityCollection) ResolveRelationships() {
+	// Names and aliases may have been changed outside of the collection
+	e.reindex()
+	for _, entity := range e.GetEntities() {
+		relationships := entity.GetRelationships()
+		entity.Relationships = nil
+		for _, entityRelationship := range relationships {
+			target, err := e.ResolveRelationshipTarget(entityRelationship.Target)
+			if err != nil {
+				e.AddWarning(fmt.Sprintf("%s: %s", entity.GetId(), err), entityRelationship.Provenance)
+			}
+			if target != nil {
+				entityRelationship.Target = target.GetId()
+			}
+			// Merging again removes relationships that are
+			// duplicates once their targets are resolved
+			entity.MergeRelationship(entityRelationship)
+		}
+	}
+}
+
+// dependentRelationship A relationship from a dependent entity
+type dependentRelationship struct {
+	id               metadata.EntityId
+	relationshipType relationship.RelationshipType
+}
+
+// getDependentRelationships Returns the relationships to an entity,
+// in the order the dependent entities were added to the collection
+func (e *EntityCollection) getDependentRelationships(id metadata.EntityId) []dependentRelationship {
+	if e.dependents == nil {
+		e.dependents = map[metadata.EntityId][]dependentRelationship{}
+		for _, entity := range e.GetEntities() {
+			for _, entityRelationship := range entity.GetRelationships() {
+				e.dependents[entityRelationship.Target] = append(e.dependents[entityRelationship.Target], dependentRelationship{
+					id:               entity.GetId(),
+					relationshipType: entityRelationship.Type,
+				})
+			}
+		}
+	}
+	return e.dependents[id]
+}
+
+// GetDependents Returns entities that have a relationship of the given type
+// to the entity. All relationship types are included if relationshipType is empty
+func (e *EntityCollection) GetDependents(id metadata.EntityId, relationshipType relationship.RelationshipType) []*metadata.Entity {
+	var dependents []*metadata.Entity
+	var previous *metadata.Entity
+	for _, dependent := range e.getDependentRelationships(id) {
+		if relationshipType != "" && dependent.relationshipType != relationshipType {
+			continue
+		}
+		// Relationships of each entity are adjacent
+		if entity := e.GetEntityById(dependent.id); entity != nil && entity != previous {
+			dependents = append(dependents, entity)
+			previous = entity
+		}
+	}
+	return dependents
+}
+
+// GetHostedEntities Returns entities hosted on the entity
+func (e *EntityCollection) GetHostedEntities(id metadata.EntityId) []*metadata.Entity {
+	return e.GetDependents(id, relationship.RelationshipTypeHost)
+}
+
+// DanglingReference A relationship to an entity that does not exist
+type DanglingReference struct {
+	EntityId     metadata.EntityId
+	Relationship metadata.Relationship
+}
+
+func (d DanglingReference) String() string {
+	message := fmt.Sprintf("%s: %s relationship to %s, which does not exist", d.EntityId, d.Relationship.Type, d.Relationship.Target)
+	if !d.Relationship.Provenance.IsZero() {
+		message = fmt.Sprintf("%s (from %s)", message, d.Relationship.Provenance)
+	}
+	return message
+}
+
+// GetDanglingReferences Returns relationships whose target is not in the collection.
+// Relationships should be resolved first; see ResolveRelationships
+func (e *EntityCollection) GetDanglingReferences() []DanglingReference {
+	var dangling []DanglingReference
+	for _, entity := range e.GetEntities() {
+		for _, entityRelationship := range entity.GetRelationships() {
+			if e.GetEntityById(entityRelationship.Target) == nil {
+				dangling = append(dangling, DanglingReference{
+					EntityId:     entity.GetId(),
+					Relationship: entityRelationship,
+				})
+			}
+		}
+	}
+	return dangling
+}
+
+// GetDependencyCycles Returns every cycle of entities in the collection that
+// depend on each other through their relationships. See DependencyGraph.GetCycles
+func (e *EntityCollection) GetDependencyCycles() ([]dependencygraph.Cycle, error) {
+	graph, err := dependencygraph.NewDependencyGraph(e.GetEntities())
+	if err != nil {
+		return nil, err
+	}
+	return graph.GetCycles(), nil
+}
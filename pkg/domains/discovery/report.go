@@ -0,0 +1,179 @@
+package discovery
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	dependencygraph "gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/dependency_graph"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/schema"
+)
+
+// FailurePolicy Determines how the EntityFactory handles failing entity sources
+type FailurePolicy string
+
+const (
+	// Stop discovery when any source fails. This is the default policy
+	FailurePolicyFailFast FailurePolicy = "fail_fast"
+	// Skip failing sources and continue with the remaining sources
+	FailurePolicyContinue FailurePolicy = "continue"
+)
+
+// DiagnosticSeverity Severity of a diagnostic raised during discovery
+type DiagnosticSeverity string
+
+const (
+	DiagnosticSeverityWarning DiagnosticSeverity = "warning"
+	DiagnosticSeverityError   DiagnosticSeverity = "error"
+)
+
+// Diagnostic A warning or error raised during discovery,
+// with the origin of the problem, where known
+type Diagnostic struct {
+	Severity   DiagnosticSeverity
+	Message    string
+	Provenance attribute.Provenance
+}
+
+func (d Diagnostic) String() string {
+	if origin := d.Provenance.Origin(); origin != "" {
+		return fmt.Sprintf("%s: %s", origin, d.Message)
+	}
+	return d.Message
+}
+
+// SourceStatus Outcome of running an entity source
+type SourceStatus string
+
+const (
+	SourceStatusSucceeded SourceStatus = "succeeded"
+	SourceStatusFailed    SourceStatus = "failed"
+	// The source was cancelled due to the failure of another source
+	SourceStatusCancelled SourceStatus = "cancelled"
+)
+
+// SourceReport Report of running a single entity source
+type SourceReport struct {
+	Name              string
+	Priority          int
+	Status            SourceStatus
+	Duration          time.Duration
+	Attempts          int
+	EntityCount       int
+	EntityCountByType map[metadata.EntityType]int
+	Warnings          []Diagnostic
+	Errors            []Diagnostic
+}
+
+func (s *SourceReport) addDiagnostics(diagnostics []Diagnostic) {
+	for _, diagnostic := range diagnostics {
+		switch diagnostic.Severity {
+		case DiagnosticSeverityError:
+			s.Errors = append(s.Errors, diagnostic)
+		default:
+			s.Warnings = append(s.Warnings, diagnostic)
+		}
+	}
+}
+
+// DiscoveryReport Report of an entity discovery run,
+// describing the outcome of each entity source
+type DiscoveryReport struct {
+	Sources     []SourceReport
+	Duration    time.Duration
+	EntityCount int
+	// Warnings Warnings that are not specific to a source
+	Warnings []Diagnostic
+	// Completeness Entities missing attributes required by their entity type schema
+	Completeness *schema.CompletenessReport
+	// Conflicts Attributes with differing values from sources of the same priority
+	Conflicts []MergeConflict
+	// EntitiesByLifecycle Deprecated and decommissioned entities.
+	// Active entities are not listed
+	EntitiesByLifecycle map[metadata.Lifecycle][]metadata.EntityId
+	// DanglingReferences Relationships to entities that were not discovered
+	DanglingReferences []DanglingReference
+	// Cycles Dependency cycles between entities, up to dependencygraph.MaxCycles
+	Cycles []dependencygraph.Cycle
+}
+
+// MergeConflict Differing values provided for an attribute at the same priority
+type MergeConflict struct {
+	EntityId  metadata.EntityId
+	Attribute attribute.AttributeName
+	// Value The value that was selected
+	Value      any
+	Candidates []attribute.AttributeCandidate
+}
+
+func (c MergeConflict) String() string {
+	parts := make([]string, 0, len(c.Candidates))
+	for _, candidate := range c.Candidates {
+		parts = append(parts, fmt.Sprintf("'%v' from %s", candidate.Value, candidate.Provenance))
+	}
+	return fmt.Sprintf("%s (%s) %s: sources disagree: %s", c.EntityId.Name, c.EntityId.Type, c.Attribute, strings.Join(parts, ", "))
+}
+
+// HasFailures Whether any entity source did not succeed
+func (r *DiscoveryReport) HasFailures() bool {
+	for _, source := range r.Sources {
+		if source.Status != SourceStatusSucceeded {
+			return true
+		}
+	}
+	return false
+}
+
+// GetFailedSources Returns the reports of sources that did not succeed
+func (r *DiscoveryReport) GetFailedSources() []SourceReport {
+	var failed []SourceReport
+	for _, source := range r.Sources {
+		if source.Status != SourceStatusSucceeded {
+			failed = append(failed, source)
+		}
+	}
+	return failed
+}
+
+func (r *DiscoveryReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Discovered %d entities from %d sources in %s\n", r.EntityCount, len(r.Sources), r.Duration.Round(time.Millisecond))
+	for _, source := range r.Sources {
+		fmt.Fprintf(&b, "%s (priority %d): %s, %d entities, %d attempts, %s\n", source.Name, source.Priority, source.Status, source.EntityCount, source.Attempts, source.Duration.Round(time.Millisecond))
+		for _, diagnostic := range source.Errors {
+			fmt.Fprintf(&b, "  ERROR: %s\n", diagnostic)
+		}
+		for _, diagnostic := range source.Warnings {
+			fmt.Fprintf(&b, "  WARNING: %s\n", diagnostic)
+		}
+	}
+	for _, diagnostic := range r.Warnings {
+		fmt.Fprintf(&b, "WARNING: %s\n", diagnostic)
+	}
+	for _, conflict := range r.Conflicts {
+		fmt.Fprintf(&b, "CONFLICT: %s\n", conflict)
+	}
+	for _, lifecycle := range []metadata.Lifecycle{metadata.LifecycleDeprecated, metadata.LifecycleDecommissioned} {
+		ids := r.EntitiesByLifecycle[lifecycle]
+		if len(ids) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(ids))
+		for _, id := range ids {
+			names = append(names, fmt.Sprintf("%s (%s)", id.Name, id.Type))
+		}
+		fmt.Fprintf(&b, "%s entities: %s\n", strings.ToUpper(string(lifecycle[:1]))+string(lifecycle[1:]), strings.Join(names, ", "))
+	}
+	for _, dangling := range r.DanglingReferences {
+		fmt.Fprintf(&b, "DANGLING REFERENCE: %s\n", dangling)
+	}
+	for _, cycle := range r.Cycles {
+		fmt.Fprintf(&b, "DEPENDENCY CYCLE: %s\n", cycle)
+	}
+	if r.Completeness != nil && len(r.Completeness.Entities) > 0 {
+		fmt.Fprintf(&b, "Incomplete entities:\n%s", r.Completeness)
+	}
+	return b.String()
+}
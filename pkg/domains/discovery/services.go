@@ -1,15 +1,28 @@
 package discovery
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/computed"
 	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/schema"
 )
 
 type EntitySource interface {
 	GetEntities(collection *EntityCollection) error
-	// Determine the priority when the entity can be run
+	// Determine the priority when the entity can be run.
+	// Sources are run in order of priority (lowest first) and
+	// the priority is applied to any attributes that the source
+	// provides without an explicit priority.
 	GetPriority() int
 	// GetEntityTypes Returns a list of entity types that the EntitySource registers
 	GetEntityTypes() []metadata.EntityType
@@ -17,95 +30,690 @@ type EntitySource interface {
 	GetAttributes() []attribute.Attribute
 }
 
+// NamedEntitySource An EntitySource that provides a name,
+// used to identify the source in attribute provenance
+type NamedEntitySource interface {
+	GetName() string
+}
+
+// getEntitySourceName Returns the name of the entity source, falling
+// back to the type name for sources without a name. Whether the source
+// provided the name is also returned. Adapted sources are named after
+// the source they wrap
+func getEntitySourceName(entitySource any) (string, bool) {
+	if adapter, ok := entitySource.(*EntitySourceAdapter); ok {
+		entitySource = adapter.entitySource
+	}
+	if namedEntitySource, ok := entitySource.(NamedEntitySource); ok {
+		if name := namedEntitySource.GetName(); name != "" {
+			return name, true
+		}
+	}
+	return fmt.Sprintf("%T", entitySource), false
+}
+
+// SchemaPolicy Determines how entities that do not match the
+// schema declared by their source are handled
+type SchemaPolicy string
+
+const (
+	// Entities are not validated against the schema
+	SchemaPolicyIgnore SchemaPolicy = "ignore"
+	// Violations are reported as warnings. This is the default policy
+	SchemaPolicyWarn SchemaPolicy = "warn"
+	// Entities with undeclared types and undeclared attributes
+	// are removed and reported as errors
+	SchemaPolicyReject SchemaPolicy = "reject"
+)
+
+// RelationshipPolicy Determines how invalid relationships are handled
+type RelationshipPolicy string
+
+const (
+	// Dangling references and dependency cycles are listed in the
+	// discovery report. This is the default policy
+	RelationshipPolicyWarn RelationshipPolicy = "warn"
+	// Discovery fails if there are dangling references or dependency cycles
+	RelationshipPolicyFail RelationshipPolicy = "fail"
+)
+
+type EntityFactoryConfig struct {
+	// FailurePolicy Determines whether discovery stops when a source fails.
+	// Defaults to FailurePolicyFailFast
+	FailurePolicy FailurePolicy
+	// SchemaPolicy Determines how schema violations are handled.
+	// Defaults to SchemaPolicyWarn
+	SchemaPolicy SchemaPolicy
+	// StrictConflictAttributes Attributes for which a merge conflict
+	// between sources of the same priority fails discovery
+	StrictConflictAttributes []attribute.AttributeName
+	// IdentityRules Rules used to merge entities that sources
+	// provide under different names
+	IdentityRules *IdentityRules
+	// RelationshipPolicy Determines how dangling references and dependency
+	// cycles are handled. Defaults to RelationshipPolicyWarn
+	RelationshipPolicy RelationshipPolicy
+}
+
 type EntityFactory struct {
-	entitySources []EntitySource
+	config             *EntityFactoryConfig
+	entitySources      []registeredEntitySource
+	schema             *schema.SchemaRegistry
+	computedAttributes *computed.ComputedAttributeService
+	transformers       []Transformer
 }
 
 func NewEntityFactory() (*EntityFactory, error) {
-	return &EntityFactory{}, nil
+	return NewEntityFactoryWithConfig(&EntityFactoryConfig{})
+}
+
+func NewEntityFactoryWithConfig(config *EntityFactoryConfig) (*EntityFactory, error) {
+	if config == nil {
+		return nil, fmt.Errorf("NewEntityFactoryWithConfig: config is nil")
+	}
+	switch config.FailurePolicy {
+	case "":
+		config.FailurePolicy = FailurePolicyFailFast
+	case FailurePolicyFailFast, FailurePolicyContinue:
+	default:
+		return nil, fmt.Errorf("NewEntityFactoryWithConfig: Unknown failure policy: %s", config.FailurePolicy)
+	}
+	switch config.SchemaPolicy {
+	case "":
+		config.SchemaPolicy = SchemaPolicyWarn
+	case SchemaPolicyIgnore, SchemaPolicyWarn, SchemaPolicyReject:
+	default:
+		return nil, fmt.Errorf("NewEntityFactoryWithConfig: Unknown schema policy: %s", config.SchemaPolicy)
+	}
+	switch config.RelationshipPolicy {
+	case "":
+		config.RelationshipPolicy = RelationshipPolicyWarn
+	case RelationshipPolicyWarn, RelationshipPolicyFail:
+	default:
+		return nil, fmt.Errorf("NewEntityFactoryWithConfig: Unknown relationship policy: %s", config.RelationshipPolicy)
+	}
+	schemaRegistry, err := schema.NewSchemaRegistry()
+	if err != nil {
+		return nil, err
+	}
+	computedAttributes, err := computed.NewComputedAttributeService()
+	if err != nil {
+		return nil, err
+	}
+	return &EntityFactory{
+		config:             config,
+		schema:             schemaRegistry,
+		computedAttributes: computedAttributes,
+	}, nil
+}
+
+// RegisterComputedAttribute Register an attribute that is computed for
+// each entity after entities from all sources have been merged
+func (m *EntityFactory) RegisterComputedAttribute(computedAttribute *computed.ComputedAttribute) error {
+	if computedAttribute == nil || computedAttribute.Attribute == nil {
+		return fmt.Errorf("RegisterComputedAttribute: computedAttribute is nil")
+	}
+	// Check the attribute against the schema before registering, to
+	// ensure a failed registration does not leave a partial schema
+	if existing := m.schema.GetAttributeByName(computedAttribute.Attribute.Name); existing != nil && existing.Type != computedAttribute.Attribute.Type {
+		return fmt.Errorf("RegisterComputedAttribute: Attribute %s already declared with type %s", existing.Name, existing.Type)
+	}
+	if err := m.computedAttributes.RegisterComputedAttribute(computedAttribute); err != nil {
+		return err
+	}
+	return m.schema.ExtendSource(computed.ComputedAttributeSource, nil, []attribute.Attribute{*computedAttribute.Attribute})
+}
+
+// GetSchema Returns the schema aggregated from all registered entity sources
+func (m *EntityFactory) GetSchema() *schema.SchemaRegistry {
+	return m.schema
 }
 
 func (m *EntityFactory) RegisterEntitySource(entitySource EntitySource) error {
+	return m.RegisterEntitySourceWithOptions(entitySource, nil)
+}
+
+// RegisterEntitySourceWithOptions Register an entity source with a timeout and retry policy.
+// Sources that also implement ContextEntitySource are run using the context.
+func (m *EntityFactory) RegisterEntitySourceWithOptions(entitySource EntitySource, options *EntitySourceOptions) error {
 	if entitySource == nil {
 		return fmt.Errorf("RegisterEntitySource: Cannot register nil entitySource")
 	}
-	m.entitySources = append(m.entitySources, entitySource)
+	if contextEntitySource, ok := entitySource.(ContextEntitySource); ok {
+		return m.RegisterContextEntitySource(contextEntitySource, options)
+	}
+	adapter, err := NewEntitySourceAdapter(entitySource)
+	if err != nil {
+		return err
+	}
+	return m.RegisterContextEntitySource(adapter, options)
+}
+
+// RegisterContextEntitySource Register a context-aware entity source.
+// options may be nil to run the source without a timeout or retries
+func (m *EntityFactory) RegisterContextEntitySource(entitySource ContextEntitySource, options *EntitySourceOptions) error {
+	if entitySource == nil {
+		return fmt.Errorf("RegisterContextEntitySource: Cannot register nil entitySource")
+	}
+	if options == nil {
+		options = &EntitySourceOptions{}
+	}
+	if options.Retries < 0 {
+		return fmt.Errorf("RegisterContextEntitySource: Retries cannot be negative")
+	}
+	name, named := getEntitySourceName(entitySource)
+	if !named {
+		// Sources without a name may be registered more than once,
+		// e.g. for different repositories, and are told apart by
+		// their position in the order of registration
+		typeName := name
+		for index := len(m.entitySources) + 1; m.schema.GetSourceSchema(name) != nil; index++ {
+			name = fmt.Sprintf("%s#%d", typeName, index)
+		}
+	}
+	if err := m.schema.RegisterSource(name, entitySource.GetEntityTypes(), entitySource.GetAttributes()); err != nil {
+		return err
+	}
+	m.entitySources = append(m.entitySources, registeredEntitySource{
+		entitySource: entitySource,
+		name:         name,
+		options:      *options,
+	})
 	return nil
 }
 
+// getOrderedEntitySources Returns entity sources ordered by priority.
+// Sources with the same priority retain their registration order
+func (m *EntityFactory) getOrderedEntitySources() []registeredEntitySource {
+	entitySources := slices.Clone(m.entitySources)
+	slices.SortStableFunc(entitySources, func(a registeredEntitySource, b registeredEntitySource) int {
+		return cmp.Compare(a.entitySource.GetPriority(), b.entitySource.GetPriority())
+	})
+	return entitySources
+}
+
 func (m *EntityFactory) LoadEntities() (*EntityCollection, error) {
+	return m.LoadEntitiesWithContext(context.Background())
+}
+
+// LoadEntitiesWithContext Run all entity sources and return the merged collection.
+// See DiscoverEntities for details of how sources are run
+func (m *EntityFactory) LoadEntitiesWithContext(ctx context.Context) (*EntityCollection, error) {
+	entityCollection, _, err := m.DiscoverEntities(ctx)
+	return entityCollection, err
+}
+
+// DiscoverEntities Run all entity sources concurrently, each into
+// its own collection, and merge the results in order of priority.
+// A report of the outcome of each source is returned, including
+// when discovery fails.
+// With FailurePolicyFailFast, the remaining sources are cancelled and
+// an error is returned if any source fails. With FailurePolicyContinue,
+// failing sources are skipped. If ctx is cancelled, an error is
+// returned regardless of the failure policy.
+func (m *EntityFactory) DiscoverEntities(ctx context.Context) (*EntityCollection, *DiscoveryReport, error) {
+	start := time.Now()
+	report := &DiscoveryReport{}
+	defer func() {
+		report.Duration = time.Since(start)
+	}()
+
 	// Create empty collection
-	entityCollection, err := NewEntityCollection()
+	entityCollection, err := NewEntityCollectionWithIdentityRules(m.config.IdentityRules)
 	if err != nil {
-		return nil, err
+		return nil, report, err
 	}
 	if entityCollection == nil {
-		return nil, fmt.Errorf("LoadEntities: Unable to create entityCollection")
+		return nil, report, fmt.Errorf("LoadEntities: Unable to create entityCollection")
+	}
+
+	parentCtx := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	entitySources := m.getOrderedEntitySources()
+	sourceCollections := make([]*EntityCollection, len(entitySources))
+	sourceReports := make([]*SourceReport, len(entitySources))
+	sourceErrors := make([]error, len(entitySources))
+
+	var wg sync.WaitGroup
+	for i, entitySource := range entitySources {
+		wg.Go(func() {
+			sourceCollections[i], sourceReports[i], sourceErrors[i] = entitySource.run(ctx)
+			if sourceErrors[i] != nil && m.config.FailurePolicy == FailurePolicyFailFast {
+				cancel()
+			}
+		})
+	}
+	wg.Wait()
+
+	// Sources that failed due to cancellation following
+	// the failure of another source are marked as cancelled
+	var firstErr error
+	for i, sourceErr := range sourceErrors {
+		if sourceErr == nil {
+			continue
+		}
+		if errors.Is(sourceErr, context.Canceled) && ctx.Err() != nil {
+			sourceReports[i].Status = SourceStatusCancelled
+		} else if firstErr == nil {
+			firstErr = sourceErr
+		}
+	}
+	for _, sourceReport := range sourceReports {
+		report.Sources = append(report.Sources, *sourceReport)
+	}
+	// Cancellation by the caller is never treated as a source failure,
+	// as the remaining collection could be partial or empty
+	if err := parentCtx.Err(); err != nil {
+		return nil, report, fmt.Errorf("Discovery cancelled: %w", err)
+	}
+	if firstErr == nil {
+		firstErr = errors.Join(sourceErrors...)
+	}
+	if firstErr != nil && m.config.FailurePolicy == FailurePolicyFailFast {
+		return nil, report, firstErr
 	}
 
-	for _, entitySource := range m.entitySources {
-		sourceCollection, err := NewEntityCollection()
-		if err != nil {
-			return nil, err
+	for i, sourceCollection := range sourceCollections {
+		if sourceCollection == nil {
+			continue
 		}
-		err = entitySource.GetEntities(sourceCollection)
-		if err != nil {
-			// @TODO: Probably return error and skip the source
-			return nil, err
+		m.validateSourceCollection(entitySources[i].name, sourceCollection, &report.Sources[i])
+		if err := entityCollection.MergeCollection(sourceCollection); err != nil {
+			return nil, report, fmt.Errorf("Error merging entities from source %s: %w", entitySources[i].name, err)
+		}
+	}
+	if err := m.runTransformers(entityCollection); err != nil {
+		return nil, report, err
+	}
+	// Resolved after transformers, which may rename or add entities
+	entityCollection.ResolveRelationships()
+	// Diagnostics raised while merging and transforming, such as ambiguous identities
+	report.Warnings = append(report.Warnings, entityCollection.GetDiagnostics()...)
+	for _, err := range m.computedAttributes.Evaluate(entityCollection.GetEntities()) {
+		report.Warnings = append(report.Warnings, Diagnostic{
+			Severity: DiagnosticSeverityWarning,
+			Message:  err.Error(),
+		})
+	}
+
+	report.EntityCount = entityCollection.Len()
+	report.Completeness = m.schema.CheckCompleteness(entityCollection.GetEntities())
+	report.Conflicts = entityCollection.GetConflicts()
+	report.EntitiesByLifecycle = map[metadata.Lifecycle][]metadata.EntityId{}
+	for _, entity := range entityCollection.GetEntities() {
+		if lifecycle := entity.GetLifecycle(); lifecycle != metadata.LifecycleActive {
+			report.EntitiesByLifecycle[lifecycle] = append(report.EntitiesByLifecycle[lifecycle], entity.GetId())
+		}
+	}
+	for _, conflict := range report.Conflicts {
+		if slices.Contains(m.config.StrictConflictAttributes, conflict.Attribute) {
+			return nil, report, fmt.Errorf("Merge conflict: %s", conflict)
+		}
+	}
+
+	report.DanglingReferences = entityCollection.GetDanglingReferences()
+	if report.Cycles, err = entityCollection.GetDependencyCycles(); err != nil {
+		return nil, report, err
+	}
+	if m.config.RelationshipPolicy == RelationshipPolicyFail {
+		if len(report.DanglingReferences) > 0 {
+			return nil, report, fmt.Errorf("Dangling reference: %s", report.DanglingReferences[0])
+		}
+		if len(report.Cycles) > 0 {
+			return nil, report, fmt.Errorf("Dependency cycle: %s", report.Cycles[0])
+		}
+	}
+	return entityCollection, report, nil
+}
+
+// validateSourceCollection Validate entities provided by a source against
+// the schema declared by the source, applying the schema policy
+func (m *EntityFactory) validateSourceCollection(sourceName string, collection *EntityCollection, sourceReport *SourceReport) {
+	if m.config.SchemaPolicy == SchemaPolicyIgnore {
+		return
+	}
+	for _, entity := range collection.GetEntities() {
+		for _, violation := range m.schema.ValidateEntity(sourceName, entity) {
+			diagnostic := Diagnostic{
+				Severity:   DiagnosticSeverityWarning,
+				Message:    violation.Message,
+				Provenance: violation.Provenance,
+			}
+			if m.config.SchemaPolicy == SchemaPolicyReject {
+				diagnostic.Severity = DiagnosticSeverityError
+				if violation.Attribute == "" {
+					collection.RemoveEntity(violation.EntityId)
+				} else {
+					entity.RemoveAttribute(violation.Attribute)
+				}
+			}
+			sourceReport.addDiagnostics([]Diagnostic{diagnostic})
 		}
-		entityCollection.MergeCollection(sourceCollection)
 	}
-	return entityCollection, nil
 }
 
+// EntityCollection Collection of entities, indexed by EntityId.
+// Entities are returned in the order they were added
 type EntityCollection struct {
-	entities []metadata.Entity
+	entities    map[metadata.EntityId]*metadata.Entity
+	entityOrder []metadata.EntityId
+	// entitySequence Position at which each entity was added. Renamed
+	// entities keep their position
+	entitySequence map[metadata.EntityId]int
+	nextSequence   int
+	diagnostics    []Diagnostic
+	identityRules  *IdentityRules
+	index          *entityIndex
+	// dependents Relationships to each entity, by target. Built when
+	// first needed and cleared when the collection changes
+	dependents map[metadata.EntityId][]dependentRelationship
 }
 
 func NewEntityCollection() (*EntityCollection, error) {
-	return &EntityCollection{}, nil
+	return NewEntityCollectionWithIdentityRules(nil)
+}
+
+// NewEntityCollectionWithIdentityRules Create a collection that merges
+// entities with different names that are identified as the same entity.
+// If identityRules is nil, entities are only matched by name and alias
+func NewEntityCollectionWithIdentityRules(identityRules *IdentityRules) (*EntityCollection, error) {
+	return &EntityCollection{
+		entities:       map[metadata.EntityId]*metadata.Entity{},
+		entityOrder:    []metadata.EntityId{},
+		entitySequence: map[metadata.EntityId]int{},
+		identityRules:  identityRules,
+		index:          newEntityIndex(identityRules),
+	}, nil
+}
+
+// GetEntityById Returns the entity stored in the collection.
+// The returned entity may be modified by the caller
+func (e *EntityCollection) GetEntityById(id metadata.EntityId) *metadata.Entity {
+	if entity, ok := e.entities[id]; ok {
+		return entity
+	}
+	return nil
 }
 
 func (e *EntityCollection) GetEntityByNameAndType(name metadata.EntityName, entityType metadata.EntityType) *metadata.Entity {
-	for _, entity := range e.entities {
-		if entity.GetName() == name && entity.GetType() == entityType {
-			return &entity
+	return e.GetEntityById(metadata.EntityId{Name: name, Type: entityType})
+}
+
+// GetEntities Returns all entities in the collection
+func (e *EntityCollection) GetEntities() []*metadata.Entity {
+	entities := make([]*metadata.Entity, 0, len(e.entityOrder))
+	for _, id := range e.entityOrder {
+		entities = append(entities, e.entities[id])
+	}
+	return entities
+}
+
+// getEntitiesInOrder Returns the entities with the given ids,
+// in the order they were added to the collection
+func (e *EntityCollection) getEntitiesInOrder(ids []metadata.EntityId) []*metadata.Entity {
+	slices.SortFunc(ids, func(a metadata.EntityId, b metadata.EntityId) int {
+		return cmp.Compare(e.entitySequence[a], e.entitySequence[b])
+	})
+	ids = slices.Compact(ids)
+	entities := make([]*metadata.Entity, 0, len(ids))
+	for _, id := range ids {
+		if entity, ok := e.entities[id]; ok {
+			entities = append(entities, entity)
 		}
 	}
+	return entities
+}
+
+// reindex Rebuild the index of entity names and match attributes,
+// following changes made to entities outside of the collection
+func (e *EntityCollection) reindex() {
+	e.dependents = nil
+	e.index.clear()
+	for _, entity := range e.GetEntities() {
+		e.index.add(entity)
+	}
+}
+
+// RemoveEntity Remove an entity from the collection
+func (e *EntityCollection) RemoveEntity(id metadata.EntityId) {
+	if _, ok := e.entities[id]; !ok {
+		return
+	}
+	e.index.remove(id)
+	e.dependents = nil
+	delete(e.entities, id)
+	delete(e.entitySequence, id)
+	e.entityOrder = slices.DeleteFunc(e.entityOrder, func(entityId metadata.EntityId) bool {
+		return entityId == id
+	})
+}
+
+// RenameEntity Rename an entity in the collection, keeping the previous
+// name as an alias. If an entity with the new name already exists,
+// the renamed entity is merged into it
+func (e *EntityCollection) RenameEntity(id metadata.EntityId, name metadata.EntityName) error {
+	entity := e.GetEntityById(id)
+	if entity == nil {
+		return fmt.Errorf("RenameEntity: Entity %s (%s) not found", id.Name, id.Type)
+	}
+	if name == "" {
+		return fmt.Errorf("RenameEntity: Cannot rename entity to empty name")
+	}
+	if name == id.Name {
+		return nil
+	}
+	e.dependents = nil
+	newId := metadata.EntityId{Name: name, Type: id.Type}
+	if existing := e.GetEntityById(newId); existing != nil {
+		if err := mergeEntities(existing, entity); err != nil {
+			return err
+		}
+		e.RemoveEntity(id)
+		e.index.update(existing)
+		return nil
+	}
+
+	e.index.remove(id)
+	entity.Name = name
+	entity.AddAlias(id.Name)
+	delete(e.entities, id)
+	e.entities[newId] = entity
+	e.entitySequence[newId] = e.entitySequence[id]
+	delete(e.entitySequence, id)
+	e.entityOrder[slices.Index(e.entityOrder, id)] = newId
+	e.index.add(entity)
 	return nil
 }
 
-func (e *EntityCollection) GetEntities() []metadata.Entity {
-	return e.entities
+// Len Returns the number of entities in the collection
+func (e *EntityCollection) Len() int {
+	return len(e.entityOrder)
+}
+
+// AddWarning Record a warning raised by an entity source,
+// such as a file or document that could not be processed
+func (e *EntityCollection) AddWarning(message string, provenance attribute.Provenance) {
+	e.diagnostics = append(e.diagnostics, Diagnostic{
+		Severity:   DiagnosticSeverityWarning,
+		Message:    message,
+		Provenance: provenance,
+	})
+}
+
+// AddError Record an error raised by an entity source that
+// did not prevent the source from completing
+func (e *EntityCollection) AddError(message string, provenance attribute.Provenance) {
+	e.diagnostics = append(e.diagnostics, Diagnostic{
+		Severity:   DiagnosticSeverityError,
+		Message:    message,
+		Provenance: provenance,
+	})
+}
+
+// GetDiagnostics Returns warnings and errors recorded on the collection
+func (e *EntityCollection) GetDiagnostics() []Diagnostic {
+	return e.diagnostics
 }
 
-func mergeEntities(original *metadata.Entity, new *metadata.Entity) {
+// getValidationWarnings Returns diagnostics for attribute values that
+// failed validation with ValidationSeverityWarning
+func (e *EntityCollection) getValidationWarnings() []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, entity := range e.GetEntities() {
+		for _, name := range slices.Sorted(maps.Keys(entity.GetAttributes())) {
+			attributeInstance := entity.GetAttributes()[name]
+			for _, validationError := range attributeInstance.ValidationWarnings {
+				diagnostics = append(diagnostics, Diagnostic{
+					Severity:   DiagnosticSeverityWarning,
+					Message:    fmt.Sprintf("%s (%s): %s", entity.GetName(), entity.GetType(), validationError),
+					Provenance: attributeInstance.Provenance,
+				})
+			}
+		}
+	}
+	return diagnostics
+}
+
+// applyDefaultPriority Apply priority to all entities and attributes
+// in the collection that do not already have a priority
+func (e *EntityCollection) applyDefaultPriority(priority int) {
+	for _, entity := range e.entities {
+		entity.ApplyDefaultPriority(priority)
+	}
+}
+
+// applyDefaultSource Apply source name to the provenance of all
+// attributes in the collection that do not already have a source
+func (e *EntityCollection) applyDefaultSource(source string) {
+	for _, entity := range e.entities {
+		entity.ApplyDefaultSource(source)
+	}
+}
+
+func mergeEntities(original *metadata.Entity, new *metadata.Entity) error {
 	if original == nil || new == nil {
-		return
+		return nil
+	}
+	if err := original.MergeAttributes(new); err != nil {
+		return fmt.Errorf("Error merging entity %s (%s): %s", new.GetName(), new.GetType(), err)
 	}
-	original.MergeAttributes(new)
+	original.MergeAliases(new)
+	original.MergeRelationships(new)
+	return nil
 }
 
+// MergeCollection Merge entities from another collection.
+// Entities are copied, so the other collection is not modified
+// by subsequent changes to this collection
 func (e *EntityCollection) MergeCollection(new *EntityCollection) error {
-	for _, newEntity := range new.entities {
-		if err := e.AddEntity(&newEntity); err != nil {
+	for _, newEntity := range new.GetEntities() {
+		if err := e.AddEntity(newEntity.Clone()); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// AddEntity Add entity to the collection.
+// If an entity with the same name and type already exists, or an
+// entity is matched by alias or the collection's identity rules,
+// the new entity is merged into it, keeping the existing entity's
+// name. Otherwise the entity is stored in the collection
 func (e *EntityCollection) AddEntity(entity *metadata.Entity) error {
+	if entity == nil {
+		return fmt.Errorf("AddEntity: entity is nil")
+	}
 	if entity.GetName() == "" {
 		return fmt.Errorf("AddEntity: Cannot add entity with empty name")
 	}
 	// Check if entity already exists
-	existing := e.GetEntityByNameAndType(entity.GetName(), entity.GetType())
+	id := entity.GetId()
+	existing := e.GetEntityById(id)
+	if existing == nil {
+		var err error
+		if existing, err = e.findMatchingEntity(entity); err != nil {
+			e.AddWarning(fmt.Sprintf("Unable to resolve identity: %s", err), attribute.Provenance{})
+		}
+	}
+	e.dependents = nil
 	if existing != nil {
-		mergeEntities(existing, entity)
-	} else {
-		// Otherwise add the entity
-		e.entities = append(e.entities, *entity)
+		if err := mergeEntities(existing, entity); err != nil {
+			return err
+		}
+		// Merging may add aliases and attribute values
+		e.index.update(existing)
+		return nil
 	}
+	// Otherwise add the entity
+	e.entities[id] = entity
+	e.entityOrder = append(e.entityOrder, id)
+	e.entitySequence[id] = e.nextSequence
+	e.nextSequence++
+	e.index.add(entity)
 	return nil
 }
+
+// GetConflicts Returns attributes for which sources of the same
+// priority provided differing values
+func (e *EntityCollection) GetConflicts() []MergeConflict {
+	var conflicts []MergeConflict
+	for _, entity := range e.GetEntities() {
+		for _, name := range slices.Sorted(maps.Keys(entity.GetAttributes())) {
+			attributeInstance := entity.GetAttributes()[name]
+			if candidates := attributeInstance.GetConflictingCandidates(); len(candidates) > 0 {
+				conflicts = append(conflicts, MergeConflict{
+					EntityId:   entity.GetId(),
+					Attribute:  name,
+					Value:      attributeInstance.Value,
+					Candidates: candidates,
+				})
+			}
+		}
+	}
+	return conflicts
+}
+
+// AttributeExplanation Describes how the value of an attribute
+// on an entity was determined
+type AttributeExplanation struct {
+	EntityId   metadata.EntityId
+	Attribute  attribute.AttributeName
+	Value      any
+	Priority   int
+	Provenance attribute.Provenance
+	// Candidates All values provided for the attribute, including
+	// values that lost during merging
+	Candidates []attribute.AttributeCandidate
+}
+
+func (a *AttributeExplanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (%s) %s = '%v' from %s, priority %d\n", a.EntityId.Name, a.EntityId.Type, a.Attribute, a.Value, a.Provenance, a.Priority)
+	for _, candidate := range a.Candidates {
+		fmt.Fprintf(&b, "  candidate '%v' from %s, priority %d\n", candidate.Value, candidate.Provenance, candidate.Priority)
+	}
+	return b.String()
+}
+
+// ExplainAttribute Returns the value of an entity's attribute,
+// along with the provenance of the value and all candidate values
+func (e *EntityCollection) ExplainAttribute(id metadata.EntityId, name attribute.AttributeName) (*AttributeExplanation, error) {
+	entity := e.GetEntityById(id)
+	if entity == nil {
+		return nil, fmt.Errorf("ExplainAttribute: Entity %s (%s) not found", id.Name, id.Type)
+	}
+	attributeInstance := entity.GetAttributeByName(name)
+	if attributeInstance == nil {
+		return nil, fmt.Errorf("ExplainAttribute: Attribute %s not set on entity %s (%s)", name, id.Name, id.Type)
+	}
+	return &AttributeExplanation{
+		EntityId:   id,
+		Attribute:  name,
+		Value:      attributeInstance.Value,
+		Priority:   attributeInstance.Priority,
+		Provenance: attributeInstance.Provenance,
+		Candidates: attributeInstance.GetCandidates(),
+	}, nil
+}
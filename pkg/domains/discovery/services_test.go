@@ -0,0 +1,117 @@
+package discovery
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+)
+
+// testEntitySource Entity source without a name
+type testEntitySource struct {
+	attributes []attribute.Attribute
+}
+
+func (s *testEntitySource) GetEntities(collection *EntityCollection) error {
+	return nil
+}
+
+func (s *testEntitySource) GetPriority() int {
+	return 0
+}
+
+func (s *testEntitySource) GetEntityTypes() []metadata.EntityType {
+	return []metadata.EntityType{"server"}
+}
+
+func (s *testEntitySource) GetAttributes() []attribute.Attribute {
+	return s.attributes
+}
+
+// testNamedEntitySource Entity source with a name
+type testNamedEntitySource struct {
+	testEntitySource
+	name string
+}
+
+func (s *testNamedEntitySource) GetName() string {
+	return s.name
+}
+
+func TestRegisterEntitySourceNames(t *testing.T) {
+	tests := []struct {
+		name          string
+		sources       []EntitySource
+		expected      []string
+		expectedError bool
+	}{
+		{
+			name:     "sources without a name are named after their type",
+			sources:  []EntitySource{&testEntitySource{}, &testEntitySource{}, &testEntitySource{}},
+			expected: []string{"*discovery.testEntitySource", "*discovery.testEntitySource#2", "*discovery.testEntitySource#3"},
+		},
+		{
+			name:     "empty name falls back to the type",
+			sources:  []EntitySource{&testNamedEntitySource{}, &testNamedEntitySource{}},
+			expected: []string{"*discovery.testNamedEntitySource", "*discovery.testNamedEntitySource#2"},
+		},
+		{
+			name:     "named sources",
+			sources:  []EntitySource{&testNamedEntitySource{name: "iac-prod"}, &testNamedEntitySource{name: "iac-staging"}},
+			expected: []string{"iac-prod", "iac-staging"},
+		},
+		{
+			name:          "duplicate names are rejected",
+			sources:       []EntitySource{&testNamedEntitySource{name: "iac"}, &testNamedEntitySource{name: "iac"}},
+			expectedError: true,
+		},
+		{
+			name: "attribute declared twice with different types",
+			sources: []EntitySource{&testEntitySource{attributes: []attribute.Attribute{
+				{Name: "port", Type: reflect.TypeOf(0)},
+				{Name: "port", Type: reflect.TypeOf("")},
+			}}},
+			expectedError: true,
+		},
+		{
+			name: "attribute declared twice with the same type",
+			sources: []EntitySource{&testEntitySource{attributes: []attribute.Attribute{
+				{Name: "port", Type: reflect.TypeOf(0)},
+				{Name: "port", Type: reflect.TypeOf(0)},
+			}}},
+			expected: []string{"*discovery.testEntitySource"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			factory, err := NewEntityFactory()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, source := range test.sources {
+				err = factory.RegisterEntitySource(source)
+				if err != nil {
+					break
+				}
+			}
+			if test.expectedError {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, source := range factory.entitySources {
+				names = append(names, source.name)
+			}
+			if !slices.Equal(names, test.expected) {
+				t.Errorf("Expected names %v, got %v", test.expected, names)
+			}
+		})
+	}
+}
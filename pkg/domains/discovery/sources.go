@@ -0,0 +1,178 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+)
+
+// ContextEntitySource An entity source that supports cancellation
+// using a context. Sources that implement this interface can be
+// stopped when a timeout is reached or discovery is cancelled.
+type ContextEntitySource interface {
+	GetEntitiesWithContext(ctx context.Context, collection *EntityCollection) error
+	// Determine the priority when the entity can be run.
+	// See EntitySource.GetPriority
+	GetPriority() int
+	// GetEntityTypes Returns a list of entity types that the EntitySource registers
+	GetEntityTypes() []metadata.EntityType
+	// GetAttributes Returns a list of attributes that the EntitySource uses
+	GetAttributes() []attribute.Attribute
+}
+
+// EntitySourceOptions Options for running an entity source
+type EntitySourceOptions struct {
+	// Timeout Maximum duration of each attempt to run the source.
+	// A zero value disables the timeout
+	Timeout time.Duration
+	// Retries Number of times to retry the source after a failure
+	Retries int
+	// RetryDelay Duration to wait between attempts
+	RetryDelay time.Duration
+}
+
+// EntitySourceAdapter Adapts an EntitySource to a ContextEntitySource.
+// The wrapped source cannot be interrupted, so when the context is
+// cancelled the adapter returns immediately and the result of the
+// source is discarded, while the source keeps running in the background.
+// Calls to the source never overlap: a retry waits for the previous
+// call to return, so timeouts do not bound the total time spent
+// running a legacy source
+type EntitySourceAdapter struct {
+	entitySource EntitySource
+	// running Holds a value while a call to the source is in progress
+	running chan struct{}
+}
+
+func NewEntitySourceAdapter(entitySource EntitySource) (*EntitySourceAdapter, error) {
+	if entitySource == nil {
+		return nil, fmt.Errorf("NewEntitySourceAdapter: entitySource is nil")
+	}
+	return &EntitySourceAdapter{
+		entitySource: entitySource,
+		running:      make(chan struct{}, 1),
+	}, nil
+}
+
+func (a *EntitySourceAdapter) GetEntitiesWithContext(ctx context.Context, collection *EntityCollection) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// Wait for any previous call, which may have been abandoned
+	// after a timeout, as the source need not be thread-safe
+	select {
+	case a.running <- struct{}{}:
+	case <-ctx.Done():
+		return fmt.Errorf("Previous call to %s has not returned: %w", a.GetName(), ctx.Err())
+	}
+	result := make(chan error, 1)
+	go func() {
+		defer func() { <-a.running }()
+		result <- a.entitySource.GetEntities(collection)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (a *EntitySourceAdapter) GetPriority() int {
+	return a.entitySource.GetPriority()
+}
+
+func (a *EntitySourceAdapter) GetEntityTypes() []metadata.EntityType {
+	return a.entitySource.GetEntityTypes()
+}
+
+func (a *EntitySourceAdapter) GetAttributes() []attribute.Attribute {
+	return a.entitySource.GetAttributes()
+}
+
+func (a *EntitySourceAdapter) GetName() string {
+	name, _ := getEntitySourceName(a.entitySource)
+	return name
+}
+
+var _ ContextEntitySource = &EntitySourceAdapter{}
+var _ NamedEntitySource = &EntitySourceAdapter{}
+
+// registeredEntitySource Entity source registered with the EntityFactory
+type registeredEntitySource struct {
+	entitySource ContextEntitySource
+	name         string
+	options      EntitySourceOptions
+}
+
+// runAttempt Run a single attempt of the entity source into a new collection
+func (r *registeredEntitySource) runAttempt(ctx context.Context) (*EntityCollection, error) {
+	if r.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.options.Timeout)
+		defer cancel()
+	}
+	collection, err := NewEntityCollection()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.entitySource.GetEntitiesWithContext(ctx, collection); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+// run Run the entity source, retrying on failure.
+// Returns the collection from the successful attempt,
+// along with a report of the run
+func (r *registeredEntitySource) run(ctx context.Context) (*EntityCollection, *SourceReport, error) {
+	report := &SourceReport{
+		Name:              r.name,
+		Priority:          r.entitySource.GetPriority(),
+		Status:            SourceStatusFailed,
+		EntityCountByType: map[metadata.EntityType]int{},
+	}
+	start := time.Now()
+	defer func() {
+		report.Duration = time.Since(start)
+	}()
+
+	var err error
+	for attempt := 0; attempt <= r.options.Retries; attempt++ {
+		if attempt > 0 && r.options.RetryDelay > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, report, fmt.Errorf("Entity source %s: %w", r.name, ctx.Err())
+			case <-time.After(r.options.RetryDelay):
+			}
+		}
+
+		report.Attempts++
+		var collection *EntityCollection
+		collection, err = r.runAttempt(ctx)
+		if err == nil {
+			collection.applyDefaultPriority(r.entitySource.GetPriority())
+			collection.applyDefaultSource(r.name)
+
+			report.Status = SourceStatusSucceeded
+			report.EntityCount = collection.Len()
+			for _, entity := range collection.GetEntities() {
+				report.EntityCountByType[entity.GetType()]++
+			}
+			report.addDiagnostics(collection.GetDiagnostics())
+			report.addDiagnostics(collection.getValidationWarnings())
+			return collection, report, nil
+		}
+		report.Errors = append(report.Errors, Diagnostic{
+			Severity: DiagnosticSeverityError,
+			Message:  fmt.Sprintf("Attempt %d failed: %s", report.Attempts, err),
+		})
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, report, fmt.Errorf("Entity source %s: %w", r.name, err)
+}
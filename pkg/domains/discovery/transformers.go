@@ -0,0 +1,46 @@
+package discovery
+
+import "fmt"
+
+// Transformer Modifies the collection of entities once entities from
+// all sources have been merged, e.g. to rename, filter or enrich entities.
+// Transformers are run in the order they were registered, before
+// relationship targets are resolved and computed attributes are evaluated
+type Transformer interface {
+	Transform(collection *EntityCollection) error
+}
+
+// NamedTransformer Optional interface for transformers to provide
+// a name, used in error messages
+type NamedTransformer interface {
+	GetName() string
+}
+
+func getTransformerName(transformer Transformer) string {
+	if namedTransformer, ok := transformer.(NamedTransformer); ok {
+		return namedTransformer.GetName()
+	}
+	return fmt.Sprintf("%T", transformer)
+}
+
+// RegisterTransformer Register a transformer, to be run after
+// any previously registered transformers
+func (m *EntityFactory) RegisterTransformer(transformer Transformer) error {
+	if transformer == nil {
+		return fmt.Errorf("RegisterTransformer: Cannot register nil transformer")
+	}
+	m.transformers = append(m.transformers, transformer)
+	return nil
+}
+
+// runTransformers Run all registered transformers on the collection, in order
+func (m *EntityFactory) runTransformers(collection *EntityCollection) error {
+	for _, transformer := range m.transformers {
+		if err := transformer.Transform(collection); err != nil {
+			return fmt.Errorf("Transformer %s: %w", getTransformerName(transformer), err)
+		}
+		// Transformers may change the names and attributes of entities directly
+		collection.reindex()
+	}
+	return nil
+}
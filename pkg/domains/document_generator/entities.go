@@ -2,6 +2,7 @@ package documentgenerator
 
 import (
 	"fmt"
+	"strings"
 
 	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
 	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
@@ -12,7 +13,19 @@ type TemplateMetadata struct {
 }
 
 type TemplateEntityShim struct {
-	Name       string
+	Name string
+	Type string
+	// Lifecycle Lifecycle state of the entity, e.g. active or deprecated
+	Lifecycle string
+	// Host Name of the entity that the entity is hosted on
+	Host string
+	// Dependencies Names of the entities that the entity depends on
+	Dependencies []string
+	// Missing Names of required attributes that are missing from the entity
+	Missing []string
+	// Impact Entities impacted by the failure of the entity.
+	// Nil unless the document generator is configured with an ImpactAnalyzer
+	Impact     *ImpactShim
 	attributes map[attribute.AttributeName]attribute.AttributeInstance
 }
 
@@ -20,10 +33,20 @@ func NewTemplateEntityShim(entity *metadata.Entity) (*TemplateEntityShim, error)
 	if entity == nil {
 		return nil, fmt.Errorf("NewTemplateEntityShim: entity is nil")
 	}
-	return &TemplateEntityShim{
-		Name:       string(entity.GetName()),
-		attributes: entity.Attributes,
-	}, nil
+	shim := &TemplateEntityShim{
+		Name:         string(entity.GetName()),
+		Type:         string(entity.GetType()),
+		Lifecycle:    string(entity.GetLifecycle()),
+		Dependencies: []string{},
+		attributes:   entity.Attributes,
+	}
+	if host := entity.GetHost(); host != nil {
+		shim.Host = string(host.Target.Name)
+	}
+	for _, dependency := range entity.GetDependencies() {
+		shim.Dependencies = append(shim.Dependencies, string(dependency.Target.Name))
+	}
+	return shim, nil
 }
 
 func (t *TemplateEntityShim) Get(attributeName string) any {
@@ -32,3 +55,37 @@ func (t *TemplateEntityShim) Get(attributeName string) any {
 	}
 	return ""
 }
+
+// Source Returns a description of where the value of an attribute originated
+func (t *TemplateEntityShim) Source(attributeName string) string {
+	if attr, ok := t.attributes[attribute.AttributeName(attributeName)]; ok {
+		return attr.Provenance.String()
+	}
+	return ""
+}
+
+// Format Get an attribute value by name, rendered as a human readable string
+func (t *TemplateEntityShim) Format(attributeName string) string {
+	return formatValue(t.Get(attributeName))
+}
+
+// HasConflict Whether sources of the same priority disagree on the value of an attribute
+func (t *TemplateEntityShim) HasConflict(attributeName string) bool {
+	if attr, ok := t.attributes[attribute.AttributeName(attributeName)]; ok {
+		return attr.HasConflict()
+	}
+	return false
+}
+
+// Conflict Describe the differing values provided for an attribute
+func (t *TemplateEntityShim) Conflict(attributeName string) string {
+	attr, ok := t.attributes[attribute.AttributeName(attributeName)]
+	if !ok {
+		return ""
+	}
+	var parts []string
+	for _, candidate := range attr.GetConflictingCandidates() {
+		parts = append(parts, fmt.Sprintf("'%s' from %s", formatValue(candidate.Value), candidate.Provenance))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,86 @@
+package documentgenerator
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// formatValue Render an attribute value as a human readable string.
+// Lists are comma separated, maps and objects are rendered as
+// "key: value" pairs ordered by key and durations use time.Duration.String
+func formatValue(value any) string {
+	if value == nil {
+		return ""
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Duration:
+		return v.String()
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	switch reflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		parts := make([]string, 0, reflectValue.Len())
+		for i := 0; i < reflectValue.Len(); i++ {
+			parts = append(parts, formatValue(reflectValue.Index(i).Interface()))
+		}
+		return strings.Join(parts, ", ")
+	case reflect.Map:
+		parts := make([]string, 0, reflectValue.Len())
+		for _, key := range sortedMapKeys(reflectValue) {
+			parts = append(parts, fmt.Sprintf("%s: %s", formatValue(key.Interface()), formatValue(reflectValue.MapIndex(key).Interface())))
+		}
+		return strings.Join(parts, ", ")
+	}
+	return fmt.Sprint(value)
+}
+
+func sortedMapKeys(mapValue reflect.Value) []reflect.Value {
+	keys := mapValue.MapKeys()
+	slices.SortFunc(keys, func(a reflect.Value, b reflect.Value) int {
+		return cmp.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+	})
+	return keys
+}
+
+// joinValues Join the elements of a list using a separator
+func joinValues(value any, separator string) string {
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() != reflect.Slice && reflectValue.Kind() != reflect.Array {
+		return formatValue(value)
+	}
+	parts := make([]string, 0, reflectValue.Len())
+	for i := 0; i < reflectValue.Len(); i++ {
+		parts = append(parts, formatValue(reflectValue.Index(i).Interface()))
+	}
+	return strings.Join(parts, separator)
+}
+
+// mapKeys Returns the keys of a map as strings, in order
+func mapKeys(value any) []string {
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() != reflect.Map {
+		return []string{}
+	}
+	keys := []string{}
+	for _, key := range sortedMapKeys(reflectValue) {
+		keys = append(keys, formatValue(key.Interface()))
+	}
+	return keys
+}
+
+// templateFunctions Functions available to all templates
+var templateFunctions = template.FuncMap{
+	"format": formatValue,
+	"join":   joinValues,
+	"keys":   mapKeys,
+}
@@ -0,0 +1,149 @@
+package documentgenerator
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/impact"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+)
+
+// ImpactReportEntityType Entity type of impact report documents. A template
+// with this entity_type in its front matter replaces the default template
+const ImpactReportEntityType metadata.EntityType = "impact_report"
+
+const impactTemplate string = `
+{{- if .Groups -}}
+Failure of {{join .Failed ", "}} impacts {{.Count}} {{if eq .Count 1}}entity{{else}}entities{{end}}.
+{{range .Groups}}
+**Depth {{.Depth}} ({{.RelationshipType}})**
+{{range .Entities}}
+- **{{.Name}}** ({{.Type}}){{with .Criticality}}, criticality {{.}}{{end}}, via {{.Via}}
+{{- end}}
+{{end}}
+{{- else -}}
+No other entities depend on {{join .Failed ", "}}.
+{{end}}`
+
+const defaultImpactReportTemplate string = `---
+entity_type: impact_report
+---
+# Impact Report: {{join .Failed ", "}}
+
+{{.Describe}}`
+
+var parsedImpactTemplate = template.Must(template.New("impact").Funcs(templateFunctions).Parse(impactTemplate))
+
+// ImpactedEntityShim Data for an impacted entity in impact templates
+type ImpactedEntityShim struct {
+	Name        string
+	Type        string
+	Criticality string
+	// Via The entity through which the entity is impacted
+	Via string
+	// Entity The impacted entity
+	Entity *TemplateEntityShim
+}
+
+// ImpactGroupShim Data for a group of impacted entities
+// with the same depth and relationship type
+type ImpactGroupShim struct {
+	Depth            int
+	RelationshipType string
+	Entities         []ImpactedEntityShim
+}
+
+// ImpactShim Data for impact templates. See impact.ImpactReport
+type ImpactShim struct {
+	// Failed Entities that are assumed to have failed
+	Failed []string
+	// Count Number of impacted entities
+	Count  int
+	Groups []ImpactGroupShim
+}
+
+func newImpactShim(report *impact.ImpactReport) (*ImpactShim, error) {
+	shim := &ImpactShim{
+		Failed: []string{},
+		Count:  len(report.Impacted),
+		Groups: []ImpactGroupShim{},
+	}
+	for _, id := range report.Failed {
+		shim.Failed = append(shim.Failed, id.String())
+	}
+	for _, group := range report.GetGroups() {
+		groupShim := ImpactGroupShim{Depth: group.Depth, RelationshipType: string(group.RelationshipType)}
+		for _, impacted := range group.Entities {
+			entityShim := ImpactedEntityShim{
+				Name:        string(impacted.Id.Name),
+				Type:        string(impacted.Id.Type),
+				Criticality: impacted.Criticality,
+				Via:         impacted.Via.String(),
+			}
+			if impacted.Entity != nil {
+				var err error
+				if entityShim.Entity, err = NewTemplateEntityShim(impacted.Entity); err != nil {
+					return nil, err
+				}
+			}
+			groupShim.Entities = append(groupShim.Entities, entityShim)
+		}
+		shim.Groups = append(shim.Groups, groupShim)
+	}
+	return shim, nil
+}
+
+// Describe Render the impacted entities, grouped by depth and relationship type
+func (s *ImpactShim) Describe() (string, error) {
+	var b bytes.Buffer
+	if err := parsedImpactTemplate.Execute(&b, s); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// IfThisFails Render an "If this fails" section, listing the entities impacted
+// by the failure of the entity. Empty unless the document generator
+// is configured with an ImpactAnalyzer
+func (t *TemplateEntityShim) IfThisFails() (string, error) {
+	if t.Impact == nil {
+		return "", nil
+	}
+	description, err := t.Impact.Describe()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("## If this fails\n\n%s", description), nil
+}
+
+// GenerateImpactReportDocument Render and store a document listing the entities
+// impacted by the failure of the given entities. Requires an ImpactAnalyzer.
+// Uses the template for ImpactReportEntityType, if there is one
+func (dg *DocumentGenerator) GenerateImpactReportDocument(name metadata.EntityName, failed ...metadata.EntityId) error {
+	if dg.config.ImpactAnalyzer == nil {
+		return fmt.Errorf("GenerateImpactReportDocument: No impact analyzer configured")
+	}
+	report, err := dg.config.ImpactAnalyzer.GetImpact(failed...)
+	if err != nil {
+		return err
+	}
+	shim, err := newImpactShim(report)
+	if err != nil {
+		return err
+	}
+
+	templateRaw, ok := dg.templates[string(ImpactReportEntityType)]
+	if !ok {
+		templateRaw = []byte(defaultImpactReportTemplate)
+	}
+	parsedTemplate, err := template.New(string(name)).Funcs(templateFunctions).Parse(string(templateRaw))
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if err := parsedTemplate.Execute(&b, shim); err != nil {
+		return err
+	}
+	return dg.documentStorage.StoreDocument(name, ImpactReportEntityType, b.Bytes())
+}
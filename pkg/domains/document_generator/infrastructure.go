@@ -5,3 +5,18 @@ import "gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
 type DocumentStorage interface {
 	StoreDocument(name metadata.EntityName, entityType metadata.EntityType, body []byte) error
 }
+
+// DocumentArchiver Optional interface for document storage that can
+// archive documents of decommissioned entities, separately from the
+// documents of active entities
+type DocumentArchiver interface {
+	ArchiveDocument(name metadata.EntityName, entityType metadata.EntityType, body []byte) error
+}
+
+// DocumentRemover Optional interface for document storage that can remove
+// a previously stored document, such as the document of an entity that
+// has since been decommissioned
+type DocumentRemover interface {
+	// RemoveDocument Remove the document, if it exists
+	RemoveDocument(name metadata.EntityName, entityType metadata.EntityType) error
+}
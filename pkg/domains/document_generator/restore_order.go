@@ -0,0 +1,103 @@
+package documentgenerator
+
+import (
+	"bytes"
+	"text/template"
+
+	dependencygraph "gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/dependency_graph"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+)
+
+const (
+	// RestoreOrderEntityType Entity type of the restore order document. A template
+	// with this entity_type in its front matter replaces the default template
+	RestoreOrderEntityType metadata.EntityType = "restore_order"
+	// RestoreOrderDocumentName Name that the restore order document is stored with
+	RestoreOrderDocumentName metadata.EntityName = "restore_order"
+)
+
+const defaultRestoreOrderTemplate string = `---
+entity_type: restore_order
+---
+# Restore Order
+
+Restore each wave in turn. Entities within a wave do not depend on each other and can be restored in parallel.
+{{range .Waves}}
+## Wave {{.Number}}
+{{range .Entities}}
+- **{{.Name}}** ({{.Type}}){{with .Host}}, hosted on {{.}}{{end}}{{with .Dependencies}}, depends on {{join . ", "}}{{end}}
+{{- end}}
+{{end}}
+{{- with .DroppedDependencies -}}
+## Ignored Dependencies
+
+These relationships were not taken into account, so the entities may be placed before their dependencies.
+{{range .}}
+- {{.}}
+{{- end}}
+{{end}}`
+
+// RestoreWaveShim Data for a wave of the restore order template
+type RestoreWaveShim struct {
+	Number   int
+	Entities []*TemplateEntityShim
+}
+
+// RestoreOrderShim Data for the restore order template
+type RestoreOrderShim struct {
+	Waves []RestoreWaveShim
+	// DroppedDependencies Descriptions of the relationships
+	// that were not taken into account
+	DroppedDependencies []string
+}
+
+func newRestoreOrderShim(plan *dependencygraph.RestorePlan) (*RestoreOrderShim, error) {
+	shim := &RestoreOrderShim{Waves: []RestoreWaveShim{}, DroppedDependencies: []string{}}
+	for _, dropped := range plan.DroppedDependencies {
+		shim.DroppedDependencies = append(shim.DroppedDependencies, dropped.String())
+	}
+	for _, wave := range plan.Waves {
+		waveShim := RestoreWaveShim{Number: wave.Number}
+		for _, entity := range wave.Entities {
+			entityShim, err := NewTemplateEntityShim(entity)
+			if err != nil {
+				return nil, err
+			}
+			waveShim.Entities = append(waveShim.Entities, entityShim)
+		}
+		shim.Waves = append(shim.Waves, waveShim)
+	}
+	return shim, nil
+}
+
+// GenerateRestoreOrderDocument Render and store a document listing the order
+// in which entities should be restored. See DependencyGraph.GetRestorePlan.
+// Uses the template for RestoreOrderEntityType, if there is one
+func (dg *DocumentGenerator) GenerateRestoreOrderDocument(entities []*metadata.Entity) error {
+	graph, err := dependencygraph.NewDependencyGraph(entities)
+	if err != nil {
+		return err
+	}
+	plan, err := graph.GetRestorePlan()
+	if err != nil {
+		return err
+	}
+	shim, err := newRestoreOrderShim(plan)
+	if err != nil {
+		return err
+	}
+
+	templateRaw, ok := dg.templates[string(RestoreOrderEntityType)]
+	if !ok {
+		templateRaw = []byte(defaultRestoreOrderTemplate)
+	}
+	parsedTemplate, err := template.New(string(RestoreOrderDocumentName)).Funcs(templateFunctions).Parse(string(templateRaw))
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if err := parsedTemplate.Execute(&b, shim); err != nil {
+		return err
+	}
+	return dg.documentStorage.StoreDocument(RestoreOrderDocumentName, RestoreOrderEntityType, b.Bytes())
+}
@@ -6,18 +6,61 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"text/template"
 
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/impact"
 	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/schema"
 	"go.yaml.in/yaml/v3"
 )
 
 const TemplateExtension string = ".md"
 
+// MissingDataPolicy Determines how entities missing required attributes are rendered
+type MissingDataPolicy string
+
+const (
+	// Documents are rendered without a warning. This is the default policy
+	MissingDataPolicyIgnore MissingDataPolicy = "ignore"
+	// Documents are rendered with a warning block listing the missing attributes
+	MissingDataPolicyWarn MissingDataPolicy = "warn"
+	// Documents are not rendered and an error is returned
+	MissingDataPolicyRefuse MissingDataPolicy = "refuse"
+)
+
+// DecommissionedPolicy Determines how documents for decommissioned entities are handled
+type DecommissionedPolicy string
+
+const (
+	// No document is generated, and any previously generated document is
+	// removed if the document storage implements DocumentRemover.
+	// This is the default policy
+	DecommissionedPolicySkip DecommissionedPolicy = "skip"
+	// The document is generated and passed to the storage's ArchiveDocument.
+	// The document storage must implement DocumentArchiver
+	DecommissionedPolicyArchive DecommissionedPolicy = "archive"
+)
+
+type DocumentGeneratorConfig struct {
+	TemplateDirectory string
+	// Schema Schema registry used to determine required attributes
+	// for each entity type. Required when MissingDataPolicy is set
+	Schema *schema.SchemaRegistry
+	// MissingDataPolicy Defaults to MissingDataPolicyIgnore
+	MissingDataPolicy MissingDataPolicy
+	// DecommissionedPolicy Defaults to DecommissionedPolicySkip
+	DecommissionedPolicy DecommissionedPolicy
+	// ImpactAnalyzer Used to list the entities impacted by the failure of each
+	// entity, see TemplateEntityShim.IfThisFails, and to generate impact reports
+	ImpactAnalyzer *impact.ImpactAnalyzer
+}
+
 type DocumentGenerator struct {
 	documentStorage   DocumentStorage
 	templateDirectory string
 	templates         map[string][]byte
+	config            *DocumentGeneratorConfig
 }
 
 func extractMetadataFromTemplate(templateData []byte) (*TemplateMetadata, error) {
@@ -72,17 +115,49 @@ func getTemplates(directory string) (map[string][]byte, error) {
 }
 
 func NewDocumentGenerator(documentStorage DocumentStorage, templateDirectory string) (*DocumentGenerator, error) {
+	return NewDocumentGeneratorWithConfig(documentStorage, &DocumentGeneratorConfig{
+		TemplateDirectory: templateDirectory,
+	})
+}
+
+func NewDocumentGeneratorWithConfig(documentStorage DocumentStorage, config *DocumentGeneratorConfig) (*DocumentGenerator, error) {
 	if documentStorage == nil {
 		return nil, fmt.Errorf("NewDocumentGenerator: documentStoage is nil")
 	}
-	templates, err := getTemplates(templateDirectory)
+	if config == nil {
+		return nil, fmt.Errorf("NewDocumentGenerator: config is nil")
+	}
+	switch config.MissingDataPolicy {
+	case "":
+		config.MissingDataPolicy = MissingDataPolicyIgnore
+	case MissingDataPolicyIgnore:
+	case MissingDataPolicyWarn, MissingDataPolicyRefuse:
+		if config.Schema == nil {
+			return nil, fmt.Errorf("NewDocumentGenerator: Schema is required for missing data policy %s", config.MissingDataPolicy)
+		}
+	default:
+		return nil, fmt.Errorf("NewDocumentGenerator: Unknown missing data policy: %s", config.MissingDataPolicy)
+	}
+	switch config.DecommissionedPolicy {
+	case "":
+		config.DecommissionedPolicy = DecommissionedPolicySkip
+	case DecommissionedPolicySkip:
+	case DecommissionedPolicyArchive:
+		if _, ok := documentStorage.(DocumentArchiver); !ok {
+			return nil, fmt.Errorf("NewDocumentGenerator: Document storage does not support archiving documents")
+		}
+	default:
+		return nil, fmt.Errorf("NewDocumentGenerator: Unknown decommissioned policy: %s", config.DecommissionedPolicy)
+	}
+	templates, err := getTemplates(config.TemplateDirectory)
 	if err != nil {
 		return nil, err
 	}
 	return &DocumentGenerator{
 		documentStorage:   documentStorage,
-		templateDirectory: templateDirectory,
+		templateDirectory: config.TemplateDirectory,
 		templates:         templates,
+		config:            config,
 	}, nil
 }
 
@@ -93,26 +168,108 @@ func (dg *DocumentGenerator) getTemplateForEntityType(entityType metadata.Entity
 	return []byte{}, fmt.Errorf("Template not found for entity type: %s", entityType)
 }
 
-func (dg *DocumentGenerator) GenerateDocumentForEntity(entity metadata.Entity) error {
+// insertAfterFrontMatter Insert a block into a rendered document,
+// after the YAML front matter, if present
+func insertAfterFrontMatter(document []byte, block []byte) []byte {
+	const frontMatterDelimiter = "---\n"
+	insertAt := 0
+	if bytes.HasPrefix(document, []byte(frontMatterDelimiter)) {
+		if end := bytes.Index(document[len(frontMatterDelimiter):], []byte("\n"+frontMatterDelimiter)); end != -1 {
+			insertAt = len(frontMatterDelimiter) + end + len("\n"+frontMatterDelimiter)
+		}
+	}
+	result := make([]byte, 0, len(document)+len(block))
+	result = append(result, document[:insertAt]...)
+	result = append(result, block...)
+	result = append(result, document[insertAt:]...)
+	return result
+}
+
+// renderMissingDataWarning Render a warning block listing missing attributes
+func renderMissingDataWarning(missing []string) []byte {
+	var b bytes.Buffer
+	b.WriteString("\n> **WARNING: MISSING DATA**\n>\n")
+	b.WriteString("> This document is incomplete. The following required attributes could not be discovered:\n>\n")
+	for _, name := range missing {
+		fmt.Fprintf(&b, "> - MISSING: %s\n", name)
+	}
+	b.WriteString("\n")
+	return b.Bytes()
+}
+
+// renderLifecycleNotice Render a notice for deprecated and decommissioned entities
+func renderLifecycleNotice(lifecycle metadata.Lifecycle) []byte {
+	switch lifecycle {
+	case metadata.LifecycleDeprecated:
+		return []byte("\n> **DEPRECATED**\n>\n> This entity is deprecated and is due to be removed.\n\n")
+	case metadata.LifecycleDecommissioned:
+		return []byte("\n> **DECOMMISSIONED**\n>\n> This entity has been decommissioned. This document is archived for reference only.\n\n")
+	}
+	return nil
+}
+
+// GenerateDocumentForEntity Render and store the document for an entity.
+// Documents for decommissioned entities are skipped or archived,
+// according to the decommissioned policy
+func (dg *DocumentGenerator) GenerateDocumentForEntity(entity *metadata.Entity) error {
+	if entity == nil {
+		return fmt.Errorf("GenerateDocumentForEntity: entity is nil")
+	}
+	if entity.IsDecommissioned() && dg.config.DecommissionedPolicy == DecommissionedPolicySkip {
+		// Remove the document generated while the entity was active
+		if remover, ok := dg.documentStorage.(DocumentRemover); ok {
+			return remover.RemoveDocument(entity.GetName(), entity.GetType())
+		}
+		return nil
+	}
 	templateRaw, err := dg.getTemplateForEntityType(entity.GetType())
 	if err != nil {
 		return err
 	}
-	templateRenderer := template.New(string(entity.GetName()))
+	templateRenderer := template.New(string(entity.GetName())).Funcs(templateFunctions)
 	parsedTemplate, err := templateRenderer.Parse(string(templateRaw))
 	if err != nil {
 		return err
 	}
 
-	entityShim, err := NewTemplateEntityShim(&entity)
+	entityShim, err := NewTemplateEntityShim(entity)
 	if err != nil {
 		return err
 	}
+	if dg.config.Schema != nil {
+		completeness := dg.config.Schema.CheckEntityCompleteness(entity)
+		for _, name := range completeness.MissingRequired {
+			entityShim.Missing = append(entityShim.Missing, string(name))
+		}
+	}
+	if dg.config.ImpactAnalyzer != nil && !entity.IsDecommissioned() {
+		report, err := dg.config.ImpactAnalyzer.GetImpact(entity.GetId())
+		if err != nil {
+			return err
+		}
+		if entityShim.Impact, err = newImpactShim(report); err != nil {
+			return err
+		}
+	}
+	if len(entityShim.Missing) > 0 && dg.config.MissingDataPolicy == MissingDataPolicyRefuse && !entity.IsDecommissioned() {
+		return fmt.Errorf("Refusing to generate document for %s (%s): Missing required attributes: %s", entity.GetName(), entity.GetType(), strings.Join(entityShim.Missing, ", "))
+	}
 
 	var b bytes.Buffer
 	err = parsedTemplate.Execute(io.Writer(&b), entityShim)
 	if err != nil {
 		return err
 	}
-	return dg.documentStorage.StoreDocument(entity.GetName(), entity.GetType(), b.Bytes())
+	document := b.Bytes()
+	if len(entityShim.Missing) > 0 && dg.config.MissingDataPolicy == MissingDataPolicyWarn && !entity.IsDecommissioned() {
+		document = insertAfterFrontMatter(document, renderMissingDataWarning(entityShim.Missing))
+	}
+	if notice := renderLifecycleNotice(entity.GetLifecycle()); notice != nil {
+		document = insertAfterFrontMatter(document, notice)
+	}
+	if entity.IsDecommissioned() {
+		// Checked when the generator was created
+		return dg.documentStorage.(DocumentArchiver).ArchiveDocument(entity.GetName(), entity.GetType(), document)
+	}
+	return dg.documentStorage.StoreDocument(entity.GetName(), entity.GetType(), document)
 }
@@ -0,0 +1,105 @@
+package impact
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/relationship"
+)
+
+// ImpactedEntity An entity that is affected, directly or
+// transitively, by the failure of another entity
+type ImpactedEntity struct {
+	Id metadata.EntityId
+	// Entity The impacted entity
+	Entity *metadata.Entity
+	// RelationshipType Type of the relationship through which the entity is impacted
+	RelationshipType relationship.RelationshipType
+	// Depth Number of relationships between the failed entity and this entity,
+	// starting at 1 for entities that depend on the failed entity directly
+	Depth int
+	// Via The entity that this entity depends on, through which it is impacted
+	Via metadata.EntityId
+	// Criticality Value of the criticality attribute, if set
+	Criticality string
+}
+
+// ImpactGroup Impacted entities with the same depth and relationship type
+type ImpactGroup struct {
+	Depth            int
+	RelationshipType relationship.RelationshipType
+	Entities         []ImpactedEntity
+}
+
+// ImpactReport Entities impacted by the failure of one or more entities
+type ImpactReport struct {
+	// Failed The entities that are assumed to have failed
+	Failed []metadata.EntityId
+	// Impacted Impacted entities, sorted by depth, type and name
+	Impacted []ImpactedEntity
+}
+
+func compareImpactedEntities(a ImpactedEntity, b ImpactedEntity) int {
+	return cmp.Or(
+		cmp.Compare(a.Depth, b.Depth),
+		cmp.Compare(a.RelationshipType, b.RelationshipType),
+		cmp.Compare(a.Id.Type, b.Id.Type),
+		cmp.Compare(a.Id.Name, b.Id.Name),
+	)
+}
+
+// GetGroups Returns the impacted entities, grouped by depth and relationship type
+func (r *ImpactReport) GetGroups() []ImpactGroup {
+	groups := []ImpactGroup{}
+	for _, impacted := range r.Impacted {
+		if len(groups) == 0 || groups[len(groups)-1].Depth != impacted.Depth || groups[len(groups)-1].RelationshipType != impacted.RelationshipType {
+			groups = append(groups, ImpactGroup{Depth: impacted.Depth, RelationshipType: impacted.RelationshipType})
+		}
+		groups[len(groups)-1].Entities = append(groups[len(groups)-1].Entities, impacted)
+	}
+	return groups
+}
+
+// GetByCriticality Returns the impacted entities with a criticality
+func (r *ImpactReport) GetByCriticality(criticality string) []ImpactedEntity {
+	var impacted []ImpactedEntity
+	for _, entity := range r.Impacted {
+		if entity.Criticality == criticality {
+			impacted = append(impacted, entity)
+		}
+	}
+	return impacted
+}
+
+// GetMaxDepth Returns the largest depth of any impacted entity
+func (r *ImpactReport) GetMaxDepth() int {
+	if len(r.Impacted) == 0 {
+		return 0
+	}
+	return slices.MaxFunc(r.Impacted, func(a ImpactedEntity, b ImpactedEntity) int {
+		return cmp.Compare(a.Depth, b.Depth)
+	}).Depth
+}
+
+func (r *ImpactReport) String() string {
+	var b strings.Builder
+	failed := make([]string, 0, len(r.Failed))
+	for _, id := range r.Failed {
+		failed = append(failed, id.String())
+	}
+	fmt.Fprintf(&b, "Failure of %s impacts %d entities\n", strings.Join(failed, ", "), len(r.Impacted))
+	for _, group := range r.GetGroups() {
+		fmt.Fprintf(&b, "Depth %d (%s):\n", group.Depth, group.RelationshipType)
+		for _, impacted := range group.Entities {
+			fmt.Fprintf(&b, "  %s via %s", impacted.Id, impacted.Via)
+			if impacted.Criticality != "" {
+				fmt.Fprintf(&b, ", criticality %s", impacted.Criticality)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
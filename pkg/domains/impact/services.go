@@ -0,0 +1,161 @@
+package impact
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	commontypes "gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/common_types"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/relationship"
+)
+
+type ImpactAnalyzerConfig struct {
+	// Entities Entities whose relationships are analyzed.
+	// Decommissioned entities, and relationships to entities that are
+	// not in the list, are not included
+	Entities []*metadata.Entity
+	// CriticalityAttribute Attribute holding the criticality of
+	// each entity. Defaults to commontypes.AttributeCriticality
+	CriticalityAttribute attribute.AttributeName
+}
+
+// ImpactAnalyzer Determines the entities affected by the failure of other
+// entities, by following the dependents of each entity. Relationships are
+// held in memory, as relationship entities named in the form type/name
+// (see metadata.EntityId), and computed once, when the analyzer is created
+type ImpactAnalyzer struct {
+	config   *ImpactAnalyzerConfig
+	entities map[metadata.EntityId]*metadata.Entity
+	// relationshipEntities Relationships of each entity, with
+	// dependents sorted by name and type
+	relationshipEntities map[metadata.EntityId]*relationship.Entity
+}
+
+func NewImpactAnalyzer(config *ImpactAnalyzerConfig) (*ImpactAnalyzer, error) {
+	if config == nil {
+		return nil, fmt.Errorf("NewImpactAnalyzer: config is nil")
+	}
+	if config.CriticalityAttribute == "" {
+		config.CriticalityAttribute = commontypes.AttributeCriticality.Name
+	}
+	analyzer := &ImpactAnalyzer{
+		config:               config,
+		entities:             map[metadata.EntityId]*metadata.Entity{},
+		relationshipEntities: map[metadata.EntityId]*relationship.Entity{},
+	}
+	for _, entity := range config.Entities {
+		if entity == nil {
+			return nil, fmt.Errorf("NewImpactAnalyzer: entity is nil")
+		}
+		if entity.IsDecommissioned() {
+			continue
+		}
+		if _, ok := analyzer.entities[entity.GetId()]; ok {
+			return nil, fmt.Errorf("NewImpactAnalyzer: Duplicate entity %s", entity.GetId())
+		}
+		analyzer.entities[entity.GetId()] = entity
+		analyzer.relationshipEntities[entity.GetId()] = &relationship.Entity{
+			Name:       entity.GetId().String(),
+			Dependents: []relationship.Relationship{},
+			DependsOn:  []relationship.Relationship{},
+		}
+	}
+	analyzer.addRelationships()
+	return analyzer, nil
+}
+
+// addRelationships Add the relationships of each entity, and
+// the corresponding dependents of their targets
+func (a *ImpactAnalyzer) addRelationships() {
+	for _, entity := range a.config.Entities {
+		id := entity.GetId()
+		if a.entities[id] != entity {
+			continue
+		}
+		relationshipEntity := a.relationshipEntities[id]
+		for _, entityRelationship := range entity.GetRelationships() {
+			target, ok := a.relationshipEntities[entityRelationship.Target]
+			if !ok {
+				continue
+			}
+			exists := slices.ContainsFunc(relationshipEntity.DependsOn, func(dependsOn relationship.Relationship) bool {
+				return dependsOn.Type == entityRelationship.Type && dependsOn.Target.Name == target.Name
+			})
+			if exists {
+				continue
+			}
+			relationshipEntity.DependsOn = append(relationshipEntity.DependsOn, relationship.Relationship{Type: entityRelationship.Type, Target: relationship.Entity{Name: target.Name}})
+			target.Dependents = append(target.Dependents, relationship.Relationship{Type: entityRelationship.Type, Target: relationship.Entity{Name: relationshipEntity.Name}})
+		}
+	}
+	for _, relationshipEntity := range a.relationshipEntities {
+		slices.SortFunc(relationshipEntity.Dependents, func(x relationship.Relationship, y relationship.Relationship) int {
+			return cmp.Or(cmp.Compare(x.Target.Name, y.Target.Name), cmp.Compare(x.Type, y.Type))
+		})
+	}
+}
+
+func (a *ImpactAnalyzer) getCriticality(entity *metadata.Entity) string {
+	if entity == nil {
+		return ""
+	}
+	instance := entity.GetAttributeByName(a.config.CriticalityAttribute)
+	if instance == nil || instance.Value == nil {
+		return ""
+	}
+	return fmt.Sprint(instance.Value)
+}
+
+// GetImpact Returns all entities that transitively depend on any of the
+// failed entities. Each impacted entity is reported once, at the
+// shortest depth from a failed entity
+func (a *ImpactAnalyzer) GetImpact(failed ...metadata.EntityId) (*ImpactReport, error) {
+	if len(failed) == 0 {
+		return nil, fmt.Errorf("GetImpact: No entities given")
+	}
+	report := &ImpactReport{
+		Failed:   []metadata.EntityId{},
+		Impacted: []ImpactedEntity{},
+	}
+	visited := map[metadata.EntityId]bool{}
+	queue := []metadata.EntityId{}
+	for _, id := range failed {
+		if _, ok := a.relationshipEntities[id]; !ok {
+			return nil, fmt.Errorf("GetImpact: Entity %s does not exist", id)
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		report.Failed = append(report.Failed, id)
+		queue = append(queue, id)
+	}
+
+	depths := map[metadata.EntityId]int{}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range a.relationshipEntities[current].Dependents {
+			id := metadata.ParseEntityId(dependent.Target.Name)
+			if visited[id] {
+				continue
+			}
+			visited[id] = true
+			depths[id] = depths[current] + 1
+			entity := a.entities[id]
+			report.Impacted = append(report.Impacted, ImpactedEntity{
+				Id:               id,
+				Entity:           entity,
+				RelationshipType: dependent.Type,
+				Depth:            depths[id],
+				Via:              current,
+				Criticality:      a.getCriticality(entity),
+			})
+			queue = append(queue, id)
+		}
+	}
+	slices.SortFunc(report.Impacted, compareImpactedEntities)
+	return report, nil
+}
@@ -0,0 +1,46 @@
+package metadata
+
+import "gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+
+// Lifecycle Lifecycle state of an entity
+type Lifecycle string
+
+const (
+	// The entity is in use. Entities without a lifecycle are active
+	LifecycleActive Lifecycle = "active"
+	// The entity is still in use, but is due to be removed
+	LifecycleDeprecated Lifecycle = "deprecated"
+	// The entity no longer exists
+	LifecycleDecommissioned Lifecycle = "decommissioned"
+)
+
+// AttributeLifecycle Lifecycle state of an entity. The state is merged by
+// priority like any other attribute, so a higher priority source can
+// decommission an entity that other sources still report
+var AttributeLifecycle = attribute.NewEnumAttribute(
+	"lifecycle",
+	string(LifecycleActive),
+	string(LifecycleDeprecated),
+	string(LifecycleDecommissioned),
+)
+
+// GetLifecycle: Returns the lifecycle state of the entity,
+// defaulting to LifecycleActive
+func (e *Entity) GetLifecycle() Lifecycle {
+	if attributeInstance := e.GetAttributeByName(AttributeLifecycle.Name); attributeInstance != nil {
+		if lifecycle, ok := attributeInstance.Value.(string); ok && lifecycle != "" {
+			return Lifecycle(lifecycle)
+		}
+	}
+	return LifecycleActive
+}
+
+// SetLifecycle: Set the lifecycle state of the entity
+func (e *Entity) SetLifecycle(lifecycle Lifecycle) error {
+	return e.SetAttribute(&AttributeLifecycle, string(lifecycle))
+}
+
+// IsDecommissioned: Whether the entity no longer exists
+func (e *Entity) IsDecommissioned() bool {
+	return e.GetLifecycle() == LifecycleDecommissioned
+}
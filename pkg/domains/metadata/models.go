@@ -2,7 +2,8 @@ package metadata
 
 import (
 	"fmt"
-	"reflect"
+	"maps"
+	"slices"
 
 	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
 )
@@ -20,6 +21,10 @@ type Entity struct {
 	Type            EntityType
 	DefaultPriority int
 	Attributes      map[attribute.AttributeName]attribute.AttributeInstance
+	// Aliases Alternative names that the entity is known by in other sources
+	Aliases []EntityName
+	// Relationships Relationships to other entities, e.g. hosts and dependencies
+	Relationships []Relationship
 }
 
 func NewEntity(name EntityName, entityType EntityType, defaultPriority int) (*Entity, error) {
@@ -36,25 +41,60 @@ func (e *Entity) SetAttribute(attribute *attribute.Attribute, value any) error {
 	return e.SetAttributeWithPriority(attribute, value, 0)
 }
 
-// SetAttribute: Set attribute of entity with overriden priority
+// SetAttribute: Set attribute of entity with overriden priority.
+// If overridePriority is 0, the entity's default priority is used.
+// An attribute that is already set may only be replaced by a value
+// with a higher priority
 func (e *Entity) SetAttributeWithPriority(attribute *attribute.Attribute, value any, overridePriority int) error {
+	return e.setAttribute(attribute, value, overridePriority, nil)
+}
+
+// SetAttributeWithProvenance: Set attribute of entity, recording where the value originated
+func (e *Entity) SetAttributeWithProvenance(attr *attribute.Attribute, value any, provenance attribute.Provenance) error {
+	return e.setAttribute(attr, value, 0, &provenance)
+}
+
+// SetAttributeByName: Set attribute of entity using an attribute registered
+// with the attribute factory, coercing the value to the attribute's type
+func (e *Entity) SetAttributeByName(attributeFactory *attribute.AttributeFactory, name attribute.AttributeName, value any) error {
+	return e.SetAttributeByNameWithProvenance(attributeFactory, name, value, attribute.Provenance{})
+}
+
+// SetAttributeByNameWithProvenance: Set attribute of entity by name, coercing
+// the value to the attribute's type and recording where the value originated
+func (e *Entity) SetAttributeByNameWithProvenance(attributeFactory *attribute.AttributeFactory, name attribute.AttributeName, value any, provenance attribute.Provenance) error {
+	if attributeFactory == nil {
+		return fmt.Errorf("SetAttributeByName: attributeFactory is nil")
+	}
+	attr, coerced, err := attributeFactory.CoerceValue(name, value)
+	if err != nil {
+		return err
+	}
+	return e.setAttribute(attr, coerced, 0, &provenance)
+}
+
+func (e *Entity) setAttribute(attribute *attribute.Attribute, value any, overridePriority int, provenance *attribute.Provenance) error {
 	if attribute == nil {
 		return fmt.Errorf("SetAttribute: attribute is nil")
 	}
-	if attributeInstance := e.GetAttributeByName(attribute.Name); attributeInstance != nil {
-		return fmt.Errorf("Attribute %s already set on instance", attribute.Name)
+	if overridePriority == 0 {
+		overridePriority = e.DefaultPriority
 	}
-
-	if reflect.TypeOf(value) != attribute.Type {
-		return fmt.Errorf("Value for attribute %s is not type %s", attribute.Name, attribute.Type)
+	existing := e.GetAttributeByName(attribute.Name)
+	if existing != nil && existing.Priority >= overridePriority {
+		return fmt.Errorf("Attribute %s already set on instance with priority %d", attribute.Name, existing.Priority)
 	}
 
-	attributeInstance := attribute.CeateInstance()
+	attributeInstance := attribute.CreateInstanceWithPriority(overridePriority)
 	if err := attributeInstance.SetValue(value); err != nil {
 		return err
 	}
-	if overridePriority == 0 {
-		overridePriority = e.DefaultPriority
+	if provenance != nil {
+		attributeInstance.Provenance = *provenance
+	}
+	// Keep the replaced values as candidates, so that they can be explained
+	if existing != nil {
+		attributeInstance.Candidates = append(slices.Clone(existing.GetCandidates()), attributeInstance.GetCandidates()...)
 	}
 
 	// Assign attribute to entity
@@ -63,11 +103,29 @@ func (e *Entity) SetAttributeWithPriority(attribute *attribute.Attribute, value
 	return nil
 }
 
+// SetAttributeInstance: Set an existing attribute instance on the entity,
+// replacing any instance of the same attribute. Used when restoring entities
+func (e *Entity) SetAttributeInstance(attributeInstance attribute.AttributeInstance) error {
+	if attributeInstance.Attribute == nil {
+		return fmt.Errorf("SetAttributeInstance: attribute is nil")
+	}
+	if err := attributeInstance.Attribute.ValidateValue(attributeInstance.Value); err != nil {
+		return err
+	}
+	e.registerAttributeInstance(attributeInstance)
+	return nil
+}
+
 // registerAttributeInstance: Register an attribute instance with entity
 func (e *Entity) registerAttributeInstance(attributeInstance attribute.AttributeInstance) {
 	e.Attributes[attributeInstance.Attribute.Name] = attributeInstance
 }
 
+// RemoveAttribute: Remove attribute from entity
+func (e *Entity) RemoveAttribute(attributeName attribute.AttributeName) {
+	delete(e.Attributes, attributeName)
+}
+
 func (e *Entity) GetName() EntityName {
 	return e.Name
 }
@@ -76,6 +134,48 @@ func (e *Entity) GetType() EntityType {
 	return e.Type
 }
 
+func (e *Entity) GetId() EntityId {
+	return EntityId{
+		Name: e.Name,
+		Type: e.Type,
+	}
+}
+
+// AddAlias: Record an alternative name for the entity
+func (e *Entity) AddAlias(alias EntityName) {
+	if alias == "" || alias == e.Name || slices.Contains(e.Aliases, alias) {
+		return
+	}
+	e.Aliases = append(e.Aliases, alias)
+}
+
+func (e *Entity) GetAliases() []EntityName {
+	return e.Aliases
+}
+
+// GetNames: Returns the name of the entity followed by its aliases
+func (e *Entity) GetNames() []EntityName {
+	return append([]EntityName{e.Name}, e.Aliases...)
+}
+
+// MergeAliases: Record the name and aliases of another
+// entity as aliases of this entity
+func (e *Entity) MergeAliases(new *Entity) {
+	for _, name := range new.GetNames() {
+		e.AddAlias(name)
+	}
+}
+
+// Clone: Returns a copy of the entity that does not
+// share attributes with the original
+func (e *Entity) Clone() *Entity {
+	clone := *e
+	clone.Attributes = maps.Clone(e.Attributes)
+	clone.Aliases = slices.Clone(e.Aliases)
+	clone.Relationships = slices.Clone(e.Relationships)
+	return &clone
+}
+
 func (e *Entity) GetAttributes() map[attribute.AttributeName]attribute.AttributeInstance {
 	return e.Attributes
 }
@@ -87,10 +187,51 @@ func (e *Entity) GetAttributeByName(attributeName attribute.AttributeName) *attr
 	return nil
 }
 
-func (e *Entity) MergeAttributes(new *Entity) {
+// MergeAttributes: Merge attributes from another entity.
+// Attributes that are not already set on the entity are added
+func (e *Entity) MergeAttributes(new *Entity) error {
 	for _, newAttribute := range new.GetAttributes() {
-		if existingAttribute := e.GetAttributeByName(newAttribute.Attribute.Name); existingAttribute != nil {
-			existingAttribute.MergeAttribute(&newAttribute)
+		existingAttribute := e.GetAttributeByName(newAttribute.Attribute.Name)
+		if existingAttribute == nil {
+			e.registerAttributeInstance(newAttribute)
+			continue
+		}
+		if err := existingAttribute.MergeAttribute(&newAttribute); err != nil {
+			return err
+		}
+		// GetAttributeByName returns a copy, so store the merged result
+		e.registerAttributeInstance(*existingAttribute)
+	}
+	return nil
+}
+
+// ApplyDefaultPriority: Apply priority to the entity and any attribute
+// instances and relationships that have not had a priority set
+func (e *Entity) ApplyDefaultPriority(priority int) {
+	if e.DefaultPriority == 0 {
+		e.DefaultPriority = priority
+	}
+	for name, attributeInstance := range e.Attributes {
+		attributeInstance.ApplyDefaultPriority(priority)
+		e.Attributes[name] = attributeInstance
+	}
+	for i := range e.Relationships {
+		if e.Relationships[i].Priority == 0 {
+			e.Relationships[i].Priority = priority
+		}
+	}
+}
+
+// ApplyDefaultSource: Apply source name to the provenance of
+// attribute instances and relationships that do not have a source set
+func (e *Entity) ApplyDefaultSource(source string) {
+	for name, attributeInstance := range e.Attributes {
+		attributeInstance.ApplyDefaultSource(source)
+		e.Attributes[name] = attributeInstance
+	}
+	for i := range e.Relationships {
+		if e.Relationships[i].Provenance.Source == "" {
+			e.Relationships[i].Provenance.Source = source
 		}
 	}
 }
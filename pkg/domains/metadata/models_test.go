@@ -0,0 +1,41 @@
+package metadata
+
+import (
+	"reflect"
+	"testing"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+)
+
+var testAttributeOwner = attribute.Attribute{Name: "owner", Type: reflect.TypeOf("")}
+
+func TestSetAttributeWithPriority(t *testing.T) {
+	entity, err := NewEntity("web-01", "server", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.SetAttributeWithProvenance(&testAttributeOwner, "platform", attribute.Provenance{Source: "iac"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.SetAttributeWithPriority(&testAttributeOwner, "payments", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.SetAttributeWithPriority(&testAttributeOwner, "data", 3); err == nil {
+		t.Error("Expected error setting a lower priority value")
+	}
+
+	attributeInstance := entity.GetAttributeByName("owner")
+	if attributeInstance.Value != "payments" || attributeInstance.Priority != 5 {
+		t.Errorf("Expected payments with priority 5, got %v with priority %d", attributeInstance.Value, attributeInstance.Priority)
+	}
+	expected := []attribute.AttributeCandidate{
+		{Value: "platform", Priority: 1, Provenance: attribute.Provenance{Source: "iac"}},
+		{Value: "payments", Priority: 5},
+	}
+	if candidates := attributeInstance.GetCandidates(); !reflect.DeepEqual(candidates, expected) {
+		t.Errorf("Expected candidates %#v, got %#v", expected, candidates)
+	}
+	if attributeInstance.HasConflict() {
+		t.Error("Expected replaced value not to conflict")
+	}
+}
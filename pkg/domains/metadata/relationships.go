@@ -0,0 +1,124 @@
+package metadata
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/relationship"
+)
+
+// String Returns the entity id in the form type/name
+func (i EntityId) String() string {
+	if i.Type == "" {
+		return string(i.Name)
+	}
+	return fmt.Sprintf("%s/%s", i.Type, i.Name)
+}
+
+// ParseEntityId Parse a reference to an entity in the form type/name, or name.
+// When only a name is given, the type is empty and the reference is
+// resolved by name once entities from all sources have been merged
+func ParseEntityId(reference string) EntityId {
+	if entityType, name, ok := strings.Cut(reference, "/"); ok {
+		return EntityId{Name: EntityName(name), Type: EntityType(entityType)}
+	}
+	return EntityId{Name: EntityName(reference)}
+}
+
+// Relationship A relationship from an entity to a target entity,
+// e.g. the server that a service is hosted on
+type Relationship struct {
+	Type relationship.RelationshipType
+	// Target Entity that the relationship refers to. Type may be empty
+	// if the source does not know the type of the target
+	Target     EntityId
+	Priority   int
+	Provenance attribute.Provenance
+}
+
+// AddRelationship: Add a relationship to the entity, using the entity's default priority.
+// An entity has at most one host relationship; see MergeRelationship
+func (e *Entity) AddRelationship(relationshipType relationship.RelationshipType, target EntityId) error {
+	return e.AddRelationshipWithProvenance(relationshipType, target, attribute.Provenance{})
+}
+
+// AddRelationshipWithProvenance: Add a relationship to the entity,
+// recording where the relationship originated
+func (e *Entity) AddRelationshipWithProvenance(relationshipType relationship.RelationshipType, target EntityId, provenance attribute.Provenance) error {
+	if target.Name == "" {
+		return fmt.Errorf("AddRelationship: Relationship target has empty name")
+	}
+	if target.Name == e.Name && (target.Type == e.Type || target.Type == "") {
+		return fmt.Errorf("AddRelationship: %s (%s) cannot have a relationship with itself", e.Name, e.Type)
+	}
+	e.MergeRelationship(Relationship{
+		Type:       relationshipType,
+		Target:     target,
+		Priority:   e.DefaultPriority,
+		Provenance: provenance,
+	})
+	return nil
+}
+
+// MergeRelationship: Merge a relationship into the entity's relationships.
+// Host relationships are single valued: a host with a higher priority
+// replaces the existing host. Other relationships are combined, keeping
+// the relationship with the highest priority for each target
+func (e *Entity) MergeRelationship(new Relationship) {
+	for i, existing := range e.Relationships {
+		if existing.Type != new.Type {
+			continue
+		}
+		if new.Type == relationship.RelationshipTypeHost || existing.Target == new.Target {
+			if new.Priority > existing.Priority {
+				e.Relationships[i] = new
+			}
+			return
+		}
+	}
+	e.Relationships = append(e.Relationships, new)
+}
+
+// MergeRelationships: Merge relationships from another entity
+func (e *Entity) MergeRelationships(new *Entity) {
+	for _, newRelationship := range new.Relationships {
+		e.MergeRelationship(newRelationship)
+	}
+}
+
+func (e *Entity) GetRelationships() []Relationship {
+	return e.Relationships
+}
+
+// GetRelationshipsByType: Returns relationships of the given type
+func (e *Entity) GetRelationshipsByType(relationshipType relationship.RelationshipType) []Relationship {
+	var relationships []Relationship
+	for _, entityRelationship := range e.Relationships {
+		if entityRelationship.Type == relationshipType {
+			relationships = append(relationships, entityRelationship)
+		}
+	}
+	return relationships
+}
+
+// GetHost: Returns the entity's host relationship, or nil if there is none
+func (e *Entity) GetHost() *Relationship {
+	if hosts := e.GetRelationshipsByType(relationship.RelationshipTypeHost); len(hosts) > 0 {
+		return &hosts[0]
+	}
+	return nil
+}
+
+// GetDependencies: Returns the entity's dependency (non-host) relationships
+func (e *Entity) GetDependencies() []Relationship {
+	return e.GetRelationshipsByType(relationship.RelationshipTypeNormal)
+}
+
+// RemoveRelationship: Remove relationships of the given type to a target
+func (e *Entity) RemoveRelationship(relationshipType relationship.RelationshipType, target EntityId) {
+	e.Relationships = slices.DeleteFunc(e.Relationships, func(entityRelationship Relationship) bool {
+		return entityRelationship.Type == relationshipType && entityRelationship.Target == target
+	})
+}
@@ -12,12 +12,34 @@ const (
 )
 
 type Relationship struct {
-	Type   RelationshipType
-	Target Entity
+	Type   RelationshipType `json:"type"`
+	Target Entity           `json:"target"`
 }
 
 type Entity struct {
-	Name       string
-	Dependents []Relationship
-	DependsOn  []Relationship
+	Name string `json:"name"`
+	// Placeholder Whether the entity was only created as the target of a
+	// relationship, rather than registered. See RelationshipService.RegisterEntity
+	Placeholder bool           `json:"placeholder,omitempty"`
+	Dependents  []Relationship `json:"dependents,omitempty"`
+	DependsOn   []Relationship `json:"depends_on,omitempty"`
+}
+
+// Clone Returns a copy of the entity that does not share relationships
+// with the original. Relationship targets are copied by name only, so
+// that copies do not grow with the depth of the relationship graph
+func (e *Entity) Clone() Entity {
+	clone := Entity{
+		Name:        e.Name,
+		Placeholder: e.Placeholder,
+		Dependents:  make([]Relationship, 0, len(e.Dependents)),
+		DependsOn:   make([]Relationship, 0, len(e.DependsOn)),
+	}
+	for _, relationship := range e.Dependents {
+		clone.Dependents = append(clone.Dependents, Relationship{Type: relationship.Type, Target: Entity{Name: relationship.Target.Name}})
+	}
+	for _, relationship := range e.DependsOn {
+		clone.DependsOn = append(clone.DependsOn, Relationship{Type: relationship.Type, Target: Entity{Name: relationship.Target.Name}})
+	}
+	return clone
 }
@@ -2,30 +2,88 @@ package relationship
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 )
 
+// RelationshipStore Storage of entities and their relationships.
+// GetEntityByName returns nil, without an error, if the entity does not exist.
+// UpdateEntities atomically updates a set of entities: update is called
+// with copies of the named entities, keyed by name and nil for entities
+// that do not exist, and the non-nil entities in the map are then stored
+// together. Nothing is stored if update returns an error
 type RelationshipStore interface {
 	GetEntityByName(name string) (*Entity, error)
+	GetEntities() ([]Entity, error)
 	UpsertEntity(entity Entity) error
+	UpdateEntities(names []string, update func(entities map[string]*Entity) error) error
+}
+
+type RelationshipServiceConfig struct {
+	// RequireRegisteredEntities Reject relationships to, or from, entities
+	// that have not been registered with RegisterEntity, rather than
+	// creating placeholder entities for them
+	RequireRegisteredEntities bool
 }
 
 type RelationshipService struct {
 	relationshipStore RelationshipStore
+	config            *RelationshipServiceConfig
+}
+
+func NewRelationshipService(relationshipStore RelationshipStore) (*RelationshipService, error) {
+	return NewRelationshipServiceWithConfig(relationshipStore, &RelationshipServiceConfig{})
 }
 
-func NewRelationshipService(relationshipStore *RelationshipStore) (*RelationshipService, error) {
+func NewRelationshipServiceWithConfig(relationshipStore RelationshipStore, config *RelationshipServiceConfig) (*RelationshipService, error) {
 	if relationshipStore == nil {
-		return nil, fmt.Errorf("NewRelationshipService: relationshipStore is nil")
+		return nil, fmt.Errorf("NewRelationshipServiceWithConfig: relationshipStore is nil")
+	}
+	if config == nil {
+		return nil, fmt.Errorf("NewRelationshipServiceWithConfig: config is nil")
 	}
 	return &RelationshipService{
-		relationshipStore: *relationshipStore,
+		relationshipStore: relationshipStore,
+		config:            config,
 	}, nil
 }
 
-func (r *RelationshipService) getOrCreateEntity(name string) (*Entity, error) {
+// getOrCreateEntity Returns the entity from a set of entities being
+// updated, creating it if it does not exist. Entities that are created
+// as the target of a relationship are placeholders, while entities
+// with relationships are known to exist and are marked as registered
+func (r *RelationshipService) getOrCreateEntity(entities map[string]*Entity, name string, isTarget bool) (*Entity, error) {
+	entity := entities[name]
+	if entity == nil {
+		if r.config.RequireRegisteredEntities {
+			return nil, fmt.Errorf("Entity %s does not exist", name)
+		}
+		entity = &Entity{
+			Name:        name,
+			Placeholder: true,
+			Dependents:  []Relationship{},
+			DependsOn:   []Relationship{},
+		}
+		entities[name] = entity
+	}
+	if entity.Placeholder && r.config.RequireRegisteredEntities {
+		return nil, fmt.Errorf("Entity %s has not been registered", name)
+	}
+	if !isTarget {
+		entity.Placeholder = false
+	}
+	return entity, nil
+}
+
+// RegisterEntity Register an entity as known to exist. Placeholder
+// entities created by earlier relationships are marked as registered
+func (r *RelationshipService) RegisterEntity(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("RegisterEntity: Entity name is empty")
+	}
 	entity, err := r.relationshipStore.GetEntityByName(name)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	if entity == nil {
 		entity = &Entity{
@@ -33,41 +91,65 @@ func (r *RelationshipService) getOrCreateEntity(name string) (*Entity, error) {
 			Dependents: []Relationship{},
 			DependsOn:  []Relationship{},
 		}
+	} else if !entity.Placeholder {
+		return nil
 	}
-	return entity, nil
+	entity.Placeholder = false
+	return r.relationshipStore.UpsertEntity(*entity)
 }
 
-func (r *RelationshipService) AddEntityRelationship(name string, parentName string, relationshipType RelationshipType) error {
-	entity, err := r.getOrCreateEntity(name)
+// GetDanglingEntities Names of placeholder entities, which were referenced
+// by relationships but never registered, and the entities referencing them
+func (r *RelationshipService) GetDanglingEntities() (map[string][]string, error) {
+	entities, err := r.relationshipStore.GetEntities()
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	parentEntity, err := r.getOrCreateEntity(parentName)
-	if err != nil {
-		return err
+	dangling := map[string][]string{}
+	for _, entity := range entities {
+		if !entity.Placeholder {
+			continue
+		}
+		var referencedBy []string
+		for _, relationship := range entity.Dependents {
+			if !slices.Contains(referencedBy, relationship.Target.Name) {
+				referencedBy = append(referencedBy, relationship.Target.Name)
+			}
+		}
+		slices.Sort(referencedBy)
+		dangling[entity.Name] = referencedBy
 	}
+	return dangling, nil
+}
 
-	// Add depdency on entity
-	entity.DependsOn = append(entity.DependsOn, Relationship{
-		Target: *parentEntity,
-		Type:   relationshipType,
-	})
+// AddEntityRelationship Add a relationship from an entity to a parent entity
+// that it depends on. Both entities are updated atomically, so concurrent
+// calls do not lose relationships
+func (r *RelationshipService) AddEntityRelationship(name string, parentName string, relationshipType RelationshipType) error {
+	return r.relationshipStore.UpdateEntities([]string{name, parentName}, func(entities map[string]*Entity) error {
+		entity, err := r.getOrCreateEntity(entities, name, false)
+		if err != nil {
+			return err
+		}
 
-	// Add relationship to parent
-	parentEntity.Dependents = append(parentEntity.Dependents, Relationship{
-		Target: *entity,
-		Type:   relationshipType,
-	})
+		parentEntity, err := r.getOrCreateEntity(entities, parentName, true)
+		if err != nil {
+			return err
+		}
 
-	// Store updated entities
-	if err = r.relationshipStore.UpsertEntity(*entity); err != nil {
-		return err
-	}
-	if err = r.relationshipStore.UpsertEntity(*parentEntity); err != nil {
-		return err
-	}
-	return nil
+		// Add depdency on entity
+		entity.DependsOn = append(entity.DependsOn, Relationship{
+			Target: Entity{Name: parentEntity.Name},
+			Type:   relationshipType,
+		})
+
+		// Add relationship to parent
+		parentEntity.Dependents = append(parentEntity.Dependents, Relationship{
+			Target: Entity{Name: entity.Name},
+			Type:   relationshipType,
+		})
+		return nil
+	})
 }
 
 func (r *RelationshipService) getEntityByName(name string) (*Entity, error) {
@@ -110,3 +192,23 @@ func (r *RelationshipService) GetEntityChildren(name string, relationshipType Re
 	}
 	return &children, nil
 }
+
+// GetEntityDependents Returns the relationships of the entities that depend on an entity
+func (r *RelationshipService) GetEntityDependents(name string) ([]Relationship, error) {
+	entity, err := r.getEntityByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(entity.Dependents), nil
+}
+
+// HasEntityRelationship Whether an entity has a relationship to a parent entity
+func (r *RelationshipService) HasEntityRelationship(name string, parentName string, relationshipType RelationshipType) (bool, error) {
+	entity, err := r.relationshipStore.GetEntityByName(name)
+	if err != nil || entity == nil {
+		return false, err
+	}
+	return slices.ContainsFunc(entity.DependsOn, func(relationship Relationship) bool {
+		return relationship.Type == relationshipType && relationship.Target.Name == parentName
+	}), nil
+}
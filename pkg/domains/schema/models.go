@@ -0,0 +1,86 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+)
+
+// Requirement Whether an attribute must be present on an entity type
+type Requirement string
+
+const (
+	RequirementRequired    Requirement = "required"
+	RequirementRecommended Requirement = "recommended"
+	RequirementOptional    Requirement = "optional"
+)
+
+// EntityTypeSchema Declares the attributes expected on entities of a type
+type EntityTypeSchema struct {
+	EntityType metadata.EntityType
+	Attributes map[attribute.AttributeName]Requirement
+}
+
+// EntityCompleteness Attributes missing from an entity,
+// according to the schema of its type
+type EntityCompleteness struct {
+	EntityId           metadata.EntityId
+	MissingRequired    []attribute.AttributeName
+	MissingRecommended []attribute.AttributeName
+}
+
+// IsComplete Whether all required attributes are present
+func (e *EntityCompleteness) IsComplete() bool {
+	return len(e.MissingRequired) == 0
+}
+
+// CompletenessReport Entities that are missing required or recommended attributes
+type CompletenessReport struct {
+	Entities []EntityCompleteness
+}
+
+// IsComplete Whether all entities have their required attributes
+func (c *CompletenessReport) IsComplete() bool {
+	for _, entity := range c.Entities {
+		if !entity.IsComplete() {
+			return false
+		}
+	}
+	return true
+}
+
+// GetIncompleteEntities Returns entities missing required attributes
+func (c *CompletenessReport) GetIncompleteEntities() []EntityCompleteness {
+	var incomplete []EntityCompleteness
+	for _, entity := range c.Entities {
+		if !entity.IsComplete() {
+			incomplete = append(incomplete, entity)
+		}
+	}
+	return incomplete
+}
+
+func joinAttributeNames(names []attribute.AttributeName) string {
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, string(name))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (c *CompletenessReport) String() string {
+	var b strings.Builder
+	for _, entity := range c.Entities {
+		fmt.Fprintf(&b, "%s (%s):", entity.EntityId.Name, entity.EntityId.Type)
+		if len(entity.MissingRequired) > 0 {
+			fmt.Fprintf(&b, " missing required: %s;", joinAttributeNames(entity.MissingRequired))
+		}
+		if len(entity.MissingRecommended) > 0 {
+			fmt.Fprintf(&b, " missing recommended: %s;", joinAttributeNames(entity.MissingRecommended))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,278 @@
+package schema
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+)
+
+// SourceSchema Entity types and attributes declared by an entity source
+type SourceSchema struct {
+	Name        string
+	EntityTypes map[metadata.EntityType]bool
+	Attributes  map[attribute.AttributeName]bool
+}
+
+// SchemaRegistry Aggregated schema of all entity sources
+type SchemaRegistry struct {
+	attributeFactory *attribute.AttributeFactory
+	// attributeSources Name of the first source that declared each attribute
+	attributeSources map[attribute.AttributeName]string
+	entityTypes      map[metadata.EntityType]bool
+	sources          map[string]*SourceSchema
+	entityTypeSchema map[metadata.EntityType]*EntityTypeSchema
+}
+
+func NewSchemaRegistry() (*SchemaRegistry, error) {
+	attributeFactory, err := attribute.NewAttributeFactory()
+	if err != nil {
+		return nil, err
+	}
+	return &SchemaRegistry{
+		attributeFactory: attributeFactory,
+		attributeSources: map[attribute.AttributeName]string{},
+		entityTypes:      map[metadata.EntityType]bool{},
+		sources:          map[string]*SourceSchema{},
+		entityTypeSchema: map[metadata.EntityType]*EntityTypeSchema{},
+	}, nil
+}
+
+// RegisterSource Register the entity types and attributes declared by a source.
+// An error is returned if a source with the same name has already been
+// registered, or if an attribute has already been declared, by this
+// or another source, with a different type
+func (s *SchemaRegistry) RegisterSource(sourceName string, entityTypes []metadata.EntityType, attributes []attribute.Attribute) error {
+	if _, ok := s.sources[sourceName]; ok {
+		return fmt.Errorf("RegisterSource: Source %s is already registered. Each source must have a unique name", sourceName)
+	}
+	if err := s.addSource(sourceName, entityTypes, attributes); err != nil {
+		return fmt.Errorf("RegisterSource: %s", err)
+	}
+	return nil
+}
+
+// ExtendSource Add entity types and attributes to the schema of a source,
+// registering the source if it does not exist.
+// See RegisterSource for how attributes are checked
+func (s *SchemaRegistry) ExtendSource(sourceName string, entityTypes []metadata.EntityType, attributes []attribute.Attribute) error {
+	if err := s.addSource(sourceName, entityTypes, attributes); err != nil {
+		return fmt.Errorf("ExtendSource: %s", err)
+	}
+	return nil
+}
+
+// addSource Add entity types and attributes to the schema of a source
+func (s *SchemaRegistry) addSource(sourceName string, entityTypes []metadata.EntityType, attributes []attribute.Attribute) error {
+	if sourceName == "" {
+		return fmt.Errorf("Source name is empty")
+	}
+	// Check all attributes before registering any,
+	// so that a failed registration makes no changes
+	declared := map[attribute.AttributeName]attribute.Attribute{}
+	for _, attr := range attributes {
+		if attr.Name == "" {
+			return fmt.Errorf("Source %s declares an attribute with an empty name", sourceName)
+		}
+		if existing := s.attributeFactory.GetAttributeByName(attr.Name); existing != nil && existing.Type != attr.Type {
+			return fmt.Errorf("Attribute %s declared with type %s by source %s and type %s by source %s", attr.Name, existing.Type, s.attributeSources[attr.Name], attr.Type, sourceName)
+		}
+		if previous, ok := declared[attr.Name]; ok && previous.Type != attr.Type {
+			return fmt.Errorf("Attribute %s declared with type %s and type %s by source %s", attr.Name, previous.Type, attr.Type, sourceName)
+		}
+		declared[attr.Name] = attr
+	}
+
+	sourceSchema, ok := s.sources[sourceName]
+	if !ok {
+		sourceSchema = &SourceSchema{
+			Name:        sourceName,
+			EntityTypes: map[metadata.EntityType]bool{},
+			Attributes:  map[attribute.AttributeName]bool{},
+		}
+		s.sources[sourceName] = sourceSchema
+	}
+	for _, entityType := range entityTypes {
+		s.entityTypes[entityType] = true
+		sourceSchema.EntityTypes[entityType] = true
+	}
+	for _, attr := range attributes {
+		if s.attributeFactory.GetAttributeByName(attr.Name) == nil {
+			if err := s.attributeFactory.RegisterAttribute(&attr); err != nil {
+				return err
+			}
+			s.attributeSources[attr.Name] = sourceName
+		}
+		sourceSchema.Attributes[attr.Name] = true
+	}
+	return nil
+}
+
+// GetAttributeFactory Returns the attribute factory containing all declared attributes
+func (s *SchemaRegistry) GetAttributeFactory() *attribute.AttributeFactory {
+	return s.attributeFactory
+}
+
+func (s *SchemaRegistry) GetAttributeByName(name attribute.AttributeName) *attribute.Attribute {
+	return s.attributeFactory.GetAttributeByName(name)
+}
+
+// GetAttributes Returns all declared attributes, ordered by name
+func (s *SchemaRegistry) GetAttributes() []attribute.Attribute {
+	attributes := slices.Collect(maps.Values(s.attributeFactory.Attributes))
+	slices.SortFunc(attributes, func(a attribute.Attribute, b attribute.Attribute) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return attributes
+}
+
+// GetEntityTypes Returns all declared entity types, ordered by name
+func (s *SchemaRegistry) GetEntityTypes() []metadata.EntityType {
+	return slices.Sorted(maps.Keys(s.entityTypes))
+}
+
+// GetSourceSchema Returns the schema declared by a source
+func (s *SchemaRegistry) GetSourceSchema(sourceName string) *SourceSchema {
+	if sourceSchema, ok := s.sources[sourceName]; ok {
+		return sourceSchema
+	}
+	return nil
+}
+
+// GetSourceNames Returns names of all registered sources, ordered by name
+func (s *SchemaRegistry) GetSourceNames() []string {
+	return slices.Sorted(maps.Keys(s.sources))
+}
+
+// SchemaViolation An entity type or attribute provided by a source
+// that does not match the schema declared by the source
+type SchemaViolation struct {
+	EntityId metadata.EntityId
+	// Attribute Name of the attribute in violation.
+	// Empty when the entity type was not declared
+	Attribute  attribute.AttributeName
+	Provenance attribute.Provenance
+	Message    string
+}
+
+func (v SchemaViolation) Error() string {
+	return v.Message
+}
+
+// ValidateEntity Validate an entity against the schema declared by a source
+func (s *SchemaRegistry) ValidateEntity(sourceName string, entity *metadata.Entity) []SchemaViolation {
+	if entity == nil {
+		return nil
+	}
+	sourceSchema := s.GetSourceSchema(sourceName)
+	if sourceSchema == nil {
+		return []SchemaViolation{{
+			EntityId: entity.GetId(),
+			Message:  fmt.Sprintf("Source %s has not declared a schema", sourceName),
+		}}
+	}
+
+	var violations []SchemaViolation
+	if !sourceSchema.EntityTypes[entity.GetType()] {
+		violations = append(violations, SchemaViolation{
+			EntityId: entity.GetId(),
+			Message:  fmt.Sprintf("Entity %s has type %s, which is not declared by source %s", entity.GetName(), entity.GetType(), sourceName),
+		})
+	}
+	for _, name := range slices.Sorted(maps.Keys(entity.GetAttributes())) {
+		attributeInstance := entity.GetAttributes()[name]
+		if !sourceSchema.Attributes[name] {
+			violations = append(violations, SchemaViolation{
+				EntityId:   entity.GetId(),
+				Attribute:  name,
+				Provenance: attributeInstance.Provenance,
+				Message:    fmt.Sprintf("Attribute %s on entity %s (%s) is not declared by source %s", name, entity.GetName(), entity.GetType(), sourceName),
+			})
+			continue
+		}
+		declared := s.GetAttributeByName(name)
+		if declared != nil && attributeInstance.Attribute != nil && declared.Type != attributeInstance.Attribute.Type {
+			violations = append(violations, SchemaViolation{
+				EntityId:   entity.GetId(),
+				Attribute:  name,
+				Provenance: attributeInstance.Provenance,
+				Message:    fmt.Sprintf("Attribute %s on entity %s (%s) has type %s, but is declared with type %s", name, entity.GetName(), entity.GetType(), attributeInstance.Attribute.Type, declared.Type),
+			})
+		}
+	}
+	return violations
+}
+
+// RegisterEntityTypeSchema Register the attribute requirements for an entity type
+func (s *SchemaRegistry) RegisterEntityTypeSchema(entityTypeSchema *EntityTypeSchema) error {
+	if entityTypeSchema == nil {
+		return fmt.Errorf("RegisterEntityTypeSchema: entityTypeSchema is nil")
+	}
+	if entityTypeSchema.EntityType == "" {
+		return fmt.Errorf("RegisterEntityTypeSchema: Entity type is empty")
+	}
+	if _, ok := s.entityTypeSchema[entityTypeSchema.EntityType]; ok {
+		return fmt.Errorf("RegisterEntityTypeSchema: Schema already registered for entity type %s", entityTypeSchema.EntityType)
+	}
+	for name, requirement := range entityTypeSchema.Attributes {
+		switch requirement {
+		case RequirementRequired, RequirementRecommended, RequirementOptional:
+		default:
+			return fmt.Errorf("RegisterEntityTypeSchema: Unknown requirement for attribute %s: %s", name, requirement)
+		}
+	}
+	s.entityTypeSchema[entityTypeSchema.EntityType] = entityTypeSchema
+	return nil
+}
+
+// GetEntityTypeSchema Returns the schema registered for an entity type
+func (s *SchemaRegistry) GetEntityTypeSchema(entityType metadata.EntityType) *EntityTypeSchema {
+	if entityTypeSchema, ok := s.entityTypeSchema[entityType]; ok {
+		return entityTypeSchema
+	}
+	return nil
+}
+
+// CheckEntityCompleteness Determine which required and recommended attributes
+// are missing from an entity. Attributes with empty values are treated as missing
+func (s *SchemaRegistry) CheckEntityCompleteness(entity *metadata.Entity) EntityCompleteness {
+	completeness := EntityCompleteness{
+		EntityId: entity.GetId(),
+	}
+	entityTypeSchema := s.GetEntityTypeSchema(entity.GetType())
+	if entityTypeSchema == nil {
+		return completeness
+	}
+	for _, name := range slices.Sorted(maps.Keys(entityTypeSchema.Attributes)) {
+		if attributeInstance := entity.GetAttributeByName(name); attributeInstance != nil && !attributeInstance.IsEmpty() {
+			continue
+		}
+		switch entityTypeSchema.Attributes[name] {
+		case RequirementRequired:
+			completeness.MissingRequired = append(completeness.MissingRequired, name)
+		case RequirementRecommended:
+			completeness.MissingRecommended = append(completeness.MissingRecommended, name)
+		}
+	}
+	return completeness
+}
+
+// CheckCompleteness Produce a report of entities that are missing
+// required or recommended attributes. Decommissioned entities are not checked
+func (s *SchemaRegistry) CheckCompleteness(entities []*metadata.Entity) *CompletenessReport {
+	report := &CompletenessReport{}
+	for _, entity := range entities {
+		// Documentation is not required for entities that no longer exist
+		if entity.IsDecommissioned() {
+			continue
+		}
+		completeness := s.CheckEntityCompleteness(entity)
+		if len(completeness.MissingRequired) > 0 || len(completeness.MissingRecommended) > 0 {
+			report.Entities = append(report.Entities, completeness)
+		}
+	}
+	return report
+}
@@ -0,0 +1,350 @@
+package snapshot
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/relationship"
+)
+
+// ChangeType Kind of change between two snapshots
+type ChangeType string
+
+const (
+	ChangeTypeAdded   ChangeType = "added"
+	ChangeTypeRemoved ChangeType = "removed"
+	ChangeTypeChanged ChangeType = "changed"
+)
+
+// DiffFormat Output format for a SnapshotDiff
+type DiffFormat string
+
+const (
+	DiffFormatText     DiffFormat = "text"
+	DiffFormatMarkdown DiffFormat = "markdown"
+	DiffFormatJson     DiffFormat = "json"
+)
+
+// AttributeChange Change to a single attribute of an entity.
+// OldValue is nil for added attributes and NewValue is nil for removed attributes
+type AttributeChange struct {
+	Name          string               `json:"name"`
+	Change        ChangeType           `json:"change"`
+	OldValue      any                  `json:"old_value,omitempty"`
+	NewValue      any                  `json:"new_value,omitempty"`
+	OldProvenance attribute.Provenance `json:"old_provenance,omitzero"`
+	NewProvenance attribute.Provenance `json:"new_provenance,omitzero"`
+}
+
+// EntityDiff Change to an entity, with the attributes that changed
+type EntityDiff struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	Change     ChangeType        `json:"change"`
+	Attributes []AttributeChange `json:"attributes"`
+}
+
+// SnapshotDiff Differences between two snapshots, ordered by entity type and name
+type SnapshotDiff struct {
+	Entities []EntityDiff `json:"entities"`
+}
+
+type snapshotEntityKey struct {
+	name       string
+	entityType string
+}
+
+// Diff Compare two snapshots. To compare against a live run,
+// create a snapshot of the collection using NewSnapshot
+func Diff(oldSnapshot *Snapshot, newSnapshot *Snapshot) (*SnapshotDiff, error) {
+	if oldSnapshot == nil || newSnapshot == nil {
+		return nil, fmt.Errorf("Diff: snapshot is nil")
+	}
+
+	oldEntities := map[snapshotEntityKey]*SnapshotEntity{}
+	for i := range oldSnapshot.Entities {
+		entity := &oldSnapshot.Entities[i]
+		oldEntities[snapshotEntityKey{entity.Name, entity.Type}] = entity
+	}
+	newEntities := map[snapshotEntityKey]*SnapshotEntity{}
+	for i := range newSnapshot.Entities {
+		entity := &newSnapshot.Entities[i]
+		newEntities[snapshotEntityKey{entity.Name, entity.Type}] = entity
+	}
+
+	diff := &SnapshotDiff{Entities: []EntityDiff{}}
+	for key, oldEntity := range oldEntities {
+		entityDiff, err := diffEntity(oldEntity, newEntities[key])
+		if err != nil {
+			return nil, err
+		}
+		if entityDiff != nil {
+			diff.Entities = append(diff.Entities, *entityDiff)
+		}
+	}
+	for key, newEntity := range newEntities {
+		if _, ok := oldEntities[key]; ok {
+			continue
+		}
+		entityDiff, err := diffEntity(nil, newEntity)
+		if err != nil {
+			return nil, err
+		}
+		diff.Entities = append(diff.Entities, *entityDiff)
+	}
+
+	slices.SortFunc(diff.Entities, func(a, b EntityDiff) int {
+		return cmp.Or(cmp.Compare(a.Type, b.Type), cmp.Compare(a.Name, b.Name))
+	})
+	return diff, nil
+}
+
+// getDiffAttributes Returns the attributes of an entity to compare, by name.
+// Relationships are compared as attributes named "relationship:<type>"
+// for host relationships, which have a single target, and
+// "relationship:<type>:<target>" for other relationships
+func getDiffAttributes(entity *SnapshotEntity) map[string]*SnapshotAttribute {
+	attributes := map[string]*SnapshotAttribute{}
+	if entity == nil {
+		return attributes
+	}
+	for i := range entity.Attributes {
+		attributes[entity.Attributes[i].Name] = &entity.Attributes[i]
+	}
+	for _, snapshotRelationship := range entity.Relationships {
+		name := fmt.Sprintf("relationship:%s", snapshotRelationship.Type)
+		if relationship.RelationshipType(snapshotRelationship.Type) != relationship.RelationshipTypeHost {
+			name = fmt.Sprintf("%s:%s", name, snapshotRelationship.Target)
+		}
+		attributes[name] = &SnapshotAttribute{
+			Name:       name,
+			Value:      snapshotRelationship.Target,
+			Priority:   snapshotRelationship.Priority,
+			Provenance: snapshotRelationship.Provenance,
+		}
+	}
+	return attributes
+}
+
+// diffEntity Compare two versions of an entity, either of which may be nil.
+// Returns nil if the entity is unchanged
+func diffEntity(oldEntity *SnapshotEntity, newEntity *SnapshotEntity) (*EntityDiff, error) {
+	entityDiff := &EntityDiff{Change: ChangeTypeChanged, Attributes: []AttributeChange{}}
+	oldAttributes := getDiffAttributes(oldEntity)
+	newAttributes := getDiffAttributes(newEntity)
+	if oldEntity != nil {
+		entityDiff.Name, entityDiff.Type = oldEntity.Name, oldEntity.Type
+	} else {
+		entityDiff.Change = ChangeTypeAdded
+	}
+	if newEntity != nil {
+		entityDiff.Name, entityDiff.Type = newEntity.Name, newEntity.Type
+	} else {
+		entityDiff.Change = ChangeTypeRemoved
+	}
+
+	for name, oldAttribute := range oldAttributes {
+		newAttribute, ok := newAttributes[name]
+		if !ok {
+			if isEmptySnapshotValue(oldAttribute.Value) {
+				continue
+			}
+			entityDiff.Attributes = append(entityDiff.Attributes, AttributeChange{
+				Name:          name,
+				Change:        ChangeTypeRemoved,
+				OldValue:      oldAttribute.Value,
+				OldProvenance: oldAttribute.Provenance,
+			})
+			continue
+		}
+		oldValue, err := canonicalValue(oldAttribute.Value)
+		if err != nil {
+			return nil, err
+		}
+		newValue, err := canonicalValue(newAttribute.Value)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(oldValue, newValue) {
+			continue
+		}
+		entityDiff.Attributes = append(entityDiff.Attributes, AttributeChange{
+			Name:          name,
+			Change:        ChangeTypeChanged,
+			OldValue:      oldAttribute.Value,
+			NewValue:      newAttribute.Value,
+			OldProvenance: oldAttribute.Provenance,
+			NewProvenance: newAttribute.Provenance,
+		})
+	}
+	for name, newAttribute := range newAttributes {
+		if _, ok := oldAttributes[name]; ok || isEmptySnapshotValue(newAttribute.Value) {
+			continue
+		}
+		entityDiff.Attributes = append(entityDiff.Attributes, AttributeChange{
+			Name:          name,
+			Change:        ChangeTypeAdded,
+			NewValue:      newAttribute.Value,
+			NewProvenance: newAttribute.Provenance,
+		})
+	}
+
+	if entityDiff.Change == ChangeTypeChanged && len(entityDiff.Attributes) == 0 {
+		return nil, nil
+	}
+	slices.SortFunc(entityDiff.Attributes, func(a, b AttributeChange) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return entityDiff, nil
+}
+
+// canonicalValue Encode a value as JSON so that values decoded from
+// different formats (e.g. int and float64) compare as equal
+func canonicalValue(value any) ([]byte, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("Diff: Unable to compare value %v: %s", value, err)
+	}
+	return encoded, nil
+}
+
+// isEmptySnapshotValue Whether a value is unset. As with attributes,
+// false and 0 are not empty
+func isEmptySnapshotValue(value any) bool {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	switch string(encoded) {
+	case "null", `""`, "[]", "{}":
+		return true
+	}
+	return false
+}
+
+// formatDiffValue Format a value for text and markdown output
+func formatDiffValue(value any) string {
+	if value == nil {
+		return "(unset)"
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
+
+// IsEmpty Whether there are no differences
+func (d *SnapshotDiff) IsEmpty() bool {
+	return len(d.Entities) == 0
+}
+
+// GetEntitiesByChange Returns the entities with the given change type
+func (d *SnapshotDiff) GetEntitiesByChange(change ChangeType) []EntityDiff {
+	var entities []EntityDiff
+	for _, entity := range d.Entities {
+		if entity.Change == change {
+			entities = append(entities, entity)
+		}
+	}
+	return entities
+}
+
+func (d *SnapshotDiff) summary() string {
+	return fmt.Sprintf("%d added, %d removed, %d changed",
+		len(d.GetEntitiesByChange(ChangeTypeAdded)),
+		len(d.GetEntitiesByChange(ChangeTypeRemoved)),
+		len(d.GetEntitiesByChange(ChangeTypeChanged)))
+}
+
+// Render Write the diff in the given format
+func (d *SnapshotDiff) Render(w io.Writer, format DiffFormat) error {
+	switch format {
+	case DiffFormatText:
+		_, err := io.WriteString(w, d.String())
+		return err
+	case DiffFormatMarkdown:
+		_, err := io.WriteString(w, d.Markdown())
+		return err
+	case DiffFormatJson:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(d)
+	}
+	return fmt.Errorf("Render: Unknown diff format: %s", format)
+}
+
+func (d *SnapshotDiff) String() string {
+	var b strings.Builder
+	if d.IsEmpty() {
+		b.WriteString("No changes\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Entities: %s\n", d.summary())
+	for _, entity := range d.Entities {
+		fmt.Fprintf(&b, "%s %s (%s)\n", entity.Change, entity.Name, entity.Type)
+		for _, change := range entity.Attributes {
+			switch change.Change {
+			case ChangeTypeAdded:
+				fmt.Fprintf(&b, "  + %s: %s (from %s)\n", change.Name, formatDiffValue(change.NewValue), change.NewProvenance)
+			case ChangeTypeRemoved:
+				fmt.Fprintf(&b, "  - %s: %s (from %s)\n", change.Name, formatDiffValue(change.OldValue), change.OldProvenance)
+			default:
+				fmt.Fprintf(&b, "  ~ %s: %s -> %s (from %s, was %s)\n", change.Name, formatDiffValue(change.OldValue), formatDiffValue(change.NewValue), change.NewProvenance, change.OldProvenance)
+			}
+		}
+	}
+	return b.String()
+}
+
+// Markdown Render the diff as a markdown changelog
+func (d *SnapshotDiff) Markdown() string {
+	var b strings.Builder
+	b.WriteString("# Infrastructure Changes\n\n")
+	if d.IsEmpty() {
+		b.WriteString("No changes.\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Entities: %s.\n", d.summary())
+	for _, change := range []ChangeType{ChangeTypeAdded, ChangeTypeRemoved, ChangeTypeChanged} {
+		entities := d.GetEntitiesByChange(change)
+		if len(entities) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s%s\n", strings.ToUpper(string(change[:1])), change[1:])
+		for _, entity := range entities {
+			fmt.Fprintf(&b, "\n### %s (%s)\n\n", entity.Name, entity.Type)
+			if len(entity.Attributes) == 0 {
+				b.WriteString("No attributes.\n")
+				continue
+			}
+			b.WriteString("| Attribute | Change | Old value | New value | Source |\n")
+			b.WriteString("| --- | --- | --- | --- | --- |\n")
+			for _, attributeChange := range entity.Attributes {
+				provenance := attributeChange.NewProvenance
+				if attributeChange.Change == ChangeTypeRemoved {
+					provenance = attributeChange.OldProvenance
+				}
+				oldValue, newValue := "", ""
+				if attributeChange.Change != ChangeTypeAdded {
+					oldValue = markdownCode(formatDiffValue(attributeChange.OldValue))
+				}
+				if attributeChange.Change != ChangeTypeRemoved {
+					newValue = markdownCode(formatDiffValue(attributeChange.NewValue))
+				}
+				fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", attributeChange.Name, attributeChange.Change, oldValue, newValue, strings.ReplaceAll(provenance.String(), "|", "\\|"))
+			}
+		}
+	}
+	return b.String()
+}
+
+func markdownCode(value string) string {
+	return "`" + strings.ReplaceAll(value, "|", "\\|") + "`"
+}
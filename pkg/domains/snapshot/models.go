@@ -0,0 +1,59 @@
+package snapshot
+
+import (
+	"time"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+)
+
+// SnapshotVersion Version of the snapshot format written by this package
+const SnapshotVersion int = 1
+
+// Format Encoding used for snapshots
+type Format string
+
+const (
+	FormatJson Format = "json"
+	FormatYaml Format = "yaml"
+)
+
+// Snapshot Serializable representation of an EntityCollection
+type Snapshot struct {
+	Version   int              `json:"version" yaml:"version"`
+	CreatedAt time.Time        `json:"created_at" yaml:"created_at"`
+	Entities  []SnapshotEntity `json:"entities" yaml:"entities"`
+}
+
+type SnapshotEntity struct {
+	Name            string                 `json:"name" yaml:"name"`
+	Type            string                 `json:"type" yaml:"type"`
+	DefaultPriority int                    `json:"default_priority" yaml:"default_priority"`
+	Aliases         []string               `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Attributes      []SnapshotAttribute    `json:"attributes" yaml:"attributes"`
+	Relationships   []SnapshotRelationship `json:"relationships,omitempty" yaml:"relationships,omitempty"`
+}
+
+type SnapshotAttribute struct {
+	Name string `json:"name" yaml:"name"`
+	// Type Name of the attribute's Go type, checked against the
+	// attribute registry when the snapshot is loaded
+	Type       string               `json:"type" yaml:"type"`
+	Value      any                  `json:"value" yaml:"value"`
+	Priority   int                  `json:"priority" yaml:"priority"`
+	Provenance attribute.Provenance `json:"provenance,omitzero" yaml:"provenance,omitempty"`
+	Candidates []SnapshotCandidate  `json:"candidates,omitempty" yaml:"candidates,omitempty"`
+}
+
+type SnapshotCandidate struct {
+	Value      any                  `json:"value" yaml:"value"`
+	Priority   int                  `json:"priority" yaml:"priority"`
+	Provenance attribute.Provenance `json:"provenance,omitzero" yaml:"provenance,omitempty"`
+}
+
+type SnapshotRelationship struct {
+	Type string `json:"type" yaml:"type"`
+	// Target Target entity in the form type/name, or name if the type is unknown
+	Target     string               `json:"target" yaml:"target"`
+	Priority   int                  `json:"priority" yaml:"priority"`
+	Provenance attribute.Provenance `json:"provenance,omitzero" yaml:"provenance,omitempty"`
+}
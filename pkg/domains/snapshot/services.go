@@ -0,0 +1,264 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"time"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/discovery"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/relationship"
+	"go.yaml.in/yaml/v3"
+)
+
+// encodeValue Convert a value to a form that can be encoded.
+// Durations, including those in lists, maps and objects, are
+// written as strings, e.g. "4h0m0s"
+func encodeValue(value any) any {
+	switch v := value.(type) {
+	case time.Duration:
+		return v.String()
+	case map[string]any:
+		encoded := make(map[string]any, len(v))
+		for key, element := range v {
+			encoded[key] = encodeValue(element)
+		}
+		return encoded
+	}
+	reflectValue := reflect.ValueOf(value)
+	if !reflectValue.IsValid() || (reflectValue.Kind() != reflect.Slice && reflectValue.Kind() != reflect.Map) || reflectValue.Type().Elem() != attribute.DurationType {
+		return value
+	}
+	if reflectValue.Kind() == reflect.Slice {
+		encoded := make([]string, 0, reflectValue.Len())
+		for i := 0; i < reflectValue.Len(); i++ {
+			encoded = append(encoded, reflectValue.Index(i).Interface().(time.Duration).String())
+		}
+		return encoded
+	}
+	encoded := reflect.MakeMapWithSize(reflect.MapOf(reflectValue.Type().Key(), reflect.TypeOf("")), reflectValue.Len())
+	iter := reflectValue.MapRange()
+	for iter.Next() {
+		encoded.SetMapIndex(iter.Key(), reflect.ValueOf(iter.Value().Interface().(time.Duration).String()))
+	}
+	return encoded.Interface()
+}
+
+// NewSnapshot Create a snapshot of all entities in a collection
+func NewSnapshot(collection *discovery.EntityCollection) (*Snapshot, error) {
+	if collection == nil {
+		return nil, fmt.Errorf("NewSnapshot: collection is nil")
+	}
+	snapshot := &Snapshot{
+		Version:   SnapshotVersion,
+		CreatedAt: time.Now().UTC(),
+		Entities:  []SnapshotEntity{},
+	}
+	for _, entity := range collection.GetEntities() {
+		snapshotEntity := SnapshotEntity{
+			Name:            string(entity.GetName()),
+			Type:            string(entity.GetType()),
+			DefaultPriority: entity.DefaultPriority,
+			Attributes:      []SnapshotAttribute{},
+		}
+		for _, alias := range entity.GetAliases() {
+			snapshotEntity.Aliases = append(snapshotEntity.Aliases, string(alias))
+		}
+		for _, name := range slices.Sorted(maps.Keys(entity.GetAttributes())) {
+			attributeInstance := entity.GetAttributes()[name]
+			if attributeInstance.Attribute == nil {
+				return nil, fmt.Errorf("NewSnapshot: Attribute %s on %s (%s) has no definition", name, entity.GetName(), entity.GetType())
+			}
+			snapshotAttribute := SnapshotAttribute{
+				Name:       string(name),
+				Type:       attributeInstance.Attribute.Type.String(),
+				Value:      encodeValue(attributeInstance.Value),
+				Priority:   attributeInstance.Priority,
+				Provenance: attributeInstance.Provenance,
+			}
+			for _, candidate := range attributeInstance.Candidates {
+				snapshotAttribute.Candidates = append(snapshotAttribute.Candidates, SnapshotCandidate{
+					Value:      encodeValue(candidate.Value),
+					Priority:   candidate.Priority,
+					Provenance: candidate.Provenance,
+				})
+			}
+			snapshotEntity.Attributes = append(snapshotEntity.Attributes, snapshotAttribute)
+		}
+		for _, entityRelationship := range entity.GetRelationships() {
+			snapshotEntity.Relationships = append(snapshotEntity.Relationships, SnapshotRelationship{
+				Type:       string(entityRelationship.Type),
+				Target:     entityRelationship.Target.String(),
+				Priority:   entityRelationship.Priority,
+				Provenance: entityRelationship.Provenance,
+			})
+		}
+		snapshot.Entities = append(snapshot.Entities, snapshotEntity)
+	}
+	return snapshot, nil
+}
+
+// FormatFromPath Determine the snapshot format from a file extension
+func FormatFromPath(path string) (Format, error) {
+	switch filepath.Ext(path) {
+	case ".json":
+		return FormatJson, nil
+	case ".yaml", ".yml":
+		return FormatYaml, nil
+	}
+	return "", fmt.Errorf("Unable to determine snapshot format for file: %s", path)
+}
+
+// Encode Write the snapshot in the given format
+func (s *Snapshot) Encode(w io.Writer, format Format) error {
+	switch format {
+	case FormatJson:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(s)
+	case FormatYaml:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(s); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+	return fmt.Errorf("Encode: Unknown snapshot format: %s", format)
+}
+
+// SaveFile Write the snapshot to a file, with the format
+// determined by the file extension
+func (s *Snapshot) SaveFile(path string) error {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := s.Encode(file, format); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadSnapshot Read a snapshot in the given format
+func LoadSnapshot(r io.Reader, format Format) (*Snapshot, error) {
+	var snapshot Snapshot
+	switch format {
+	case FormatJson:
+		decoder := json.NewDecoder(r)
+		// Keep numbers exact, as float64 cannot represent every int64
+		decoder.UseNumber()
+		if err := decoder.Decode(&snapshot); err != nil {
+			return nil, fmt.Errorf("LoadSnapshot: Error decoding JSON: %s", err)
+		}
+	case FormatYaml:
+		if err := yaml.NewDecoder(r).Decode(&snapshot); err != nil {
+			return nil, fmt.Errorf("LoadSnapshot: Error decoding YAML: %s", err)
+		}
+	default:
+		return nil, fmt.Errorf("LoadSnapshot: Unknown snapshot format: %s", format)
+	}
+	if snapshot.Version != SnapshotVersion {
+		return nil, fmt.Errorf("LoadSnapshot: Unsupported snapshot version: %d", snapshot.Version)
+	}
+	return &snapshot, nil
+}
+
+// LoadFile Read a snapshot from a file, with the format
+// determined by the file extension
+func LoadFile(path string) (*Snapshot, error) {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadSnapshot(file, format)
+}
+
+// ToCollection Restore the entities in the snapshot to a collection.
+// Attributes are resolved by name through the attribute factory, which
+// must contain every attribute in the snapshot with the same type
+func (s *Snapshot) ToCollection(attributeFactory *attribute.AttributeFactory) (*discovery.EntityCollection, error) {
+	if attributeFactory == nil {
+		return nil, fmt.Errorf("ToCollection: attributeFactory is nil")
+	}
+	collection, err := discovery.NewEntityCollection()
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshotEntity := range s.Entities {
+		entity, err := metadata.NewEntity(metadata.EntityName(snapshotEntity.Name), metadata.EntityType(snapshotEntity.Type), snapshotEntity.DefaultPriority)
+		if err != nil {
+			return nil, err
+		}
+		for _, alias := range snapshotEntity.Aliases {
+			entity.AddAlias(metadata.EntityName(alias))
+		}
+		for _, snapshotAttribute := range snapshotEntity.Attributes {
+			attributeInstance, err := snapshotAttribute.toAttributeInstance(attributeFactory)
+			if err != nil {
+				return nil, fmt.Errorf("ToCollection: %s (%s): %s", snapshotEntity.Name, snapshotEntity.Type, err)
+			}
+			if err := entity.SetAttributeInstance(*attributeInstance); err != nil {
+				return nil, fmt.Errorf("ToCollection: %s (%s): %s", snapshotEntity.Name, snapshotEntity.Type, err)
+			}
+		}
+		for _, snapshotRelationship := range snapshotEntity.Relationships {
+			entity.MergeRelationship(metadata.Relationship{
+				Type:       relationship.RelationshipType(snapshotRelationship.Type),
+				Target:     metadata.ParseEntityId(snapshotRelationship.Target),
+				Priority:   snapshotRelationship.Priority,
+				Provenance: snapshotRelationship.Provenance,
+			})
+		}
+		if err := collection.AddEntity(entity); err != nil {
+			return nil, err
+		}
+	}
+	return collection, nil
+}
+
+func (s *SnapshotAttribute) toAttributeInstance(attributeFactory *attribute.AttributeFactory) (*attribute.AttributeInstance, error) {
+	attr := attributeFactory.GetAttributeByName(attribute.AttributeName(s.Name))
+	if attr == nil {
+		return nil, fmt.Errorf("Attribute %s is not registered", s.Name)
+	}
+	if attr.Type.String() != s.Type {
+		return nil, fmt.Errorf("Attribute %s has type %s in snapshot, but is registered with type %s", s.Name, s.Type, attr.Type)
+	}
+	value, err := attr.CoerceValue(s.Value)
+	if err != nil {
+		return nil, err
+	}
+	attributeInstance := attr.CreateInstanceWithPriority(s.Priority)
+	attributeInstance.Value = value
+	attributeInstance.Provenance = s.Provenance
+	for _, candidate := range s.Candidates {
+		candidateValue, err := attr.CoerceValue(candidate.Value)
+		if err != nil {
+			return nil, err
+		}
+		attributeInstance.Candidates = append(attributeInstance.Candidates, attribute.AttributeCandidate{
+			Value:      candidateValue,
+			Priority:   candidate.Priority,
+			Provenance: candidate.Provenance,
+		})
+	}
+	return &attributeInstance, nil
+}
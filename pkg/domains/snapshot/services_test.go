@@ -0,0 +1,173 @@
+package snapshot
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/discovery"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/relationship"
+)
+
+var testAttributes = []attribute.Attribute{
+	{Name: "owner", Type: reflect.TypeOf("")},
+	{Name: "enabled", Type: reflect.TypeOf(false)},
+	{Name: "load", Type: reflect.TypeOf(0.0)},
+	{Name: "inode", Type: reflect.TypeOf(int64(0))},
+	{Name: "generation", Type: reflect.TypeOf(uint64(0))},
+	{Name: "rto", Type: attribute.DurationType},
+	{Name: "windows", Type: reflect.TypeOf([]time.Duration{})},
+	{Name: "retention", Type: reflect.TypeOf(map[string]time.Duration{})},
+	{Name: "ports", Type: reflect.TypeOf(map[string]int{})},
+	{Name: "tags", Type: reflect.TypeOf([]string{})},
+	{
+		Name: "backup",
+		Type: attribute.ObjectType,
+		Fields: []attribute.Attribute{
+			{Name: "interval", Type: attribute.DurationType},
+			{Name: "copies", Type: reflect.TypeOf(0)},
+			{Name: "target", Type: reflect.TypeOf("")},
+		},
+	},
+}
+
+// testAttributeValues Values that cannot be represented exactly as
+// float64, or that are encoded as strings, within lists, maps and objects
+var testAttributeValues = map[attribute.AttributeName]any{
+	"owner":      "platform",
+	"enabled":    false,
+	"load":       0.75,
+	"inode":      int64(1152921504606846977),
+	"generation": uint64(18446744073709551615),
+	"rto":        4 * time.Hour,
+	"windows":    []time.Duration{30 * time.Minute, 90 * time.Second},
+	"retention":  map[string]time.Duration{"daily": 7 * 24 * time.Hour},
+	"ports":      map[string]int{"https": 443},
+	"tags":       []string{"web", "production"},
+	"backup":     map[string]any{"interval": 24 * time.Hour, "copies": 3, "target": "s3://backups"},
+}
+
+func newTestAttributeFactory(t *testing.T) *attribute.AttributeFactory {
+	t.Helper()
+	attributeFactory, err := attribute.NewAttributeFactory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range testAttributes {
+		if err := attributeFactory.RegisterAttribute(&testAttributes[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return attributeFactory
+}
+
+func newTestCollection(t *testing.T, attributeFactory *attribute.AttributeFactory) *discovery.EntityCollection {
+	t.Helper()
+	collection, err := discovery.NewEntityCollection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity, err := metadata.NewEntity("web-01", "server", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity.AddAlias("i-0abc")
+	for name, value := range testAttributeValues {
+		if err := entity.SetAttributeWithProvenance(attributeFactory.GetAttributeByName(name), value, attribute.Provenance{Source: "inventory"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entity.MergeRelationship(metadata.Relationship{Type: relationship.RelationshipTypeNormal, Target: metadata.ParseEntityId("service/dns")})
+	if err := collection.AddEntity(entity); err != nil {
+		t.Fatal(err)
+	}
+	return collection
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatJson, FormatYaml} {
+		t.Run(string(format), func(t *testing.T) {
+			attributeFactory := newTestAttributeFactory(t)
+			snapshot, err := NewSnapshot(newTestCollection(t, attributeFactory))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b bytes.Buffer
+			if err := snapshot.Encode(&b, format); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := LoadSnapshot(&b, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			diff, err := Diff(snapshot, loaded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !diff.IsEmpty() {
+				t.Errorf("Expected no differences, got:\n%s", diff)
+			}
+
+			collection, err := loaded.ToCollection(attributeFactory)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entity := collection.GetEntityByNameAndType("web-01", "server")
+			if entity == nil {
+				t.Fatal("Expected entity server/web-01")
+			}
+			for name, expected := range testAttributeValues {
+				attributeInstance := entity.GetAttributeByName(name)
+				if attributeInstance == nil {
+					t.Errorf("Expected attribute %s", name)
+					continue
+				}
+				if !reflect.DeepEqual(attributeInstance.Value, expected) {
+					t.Errorf("Attribute %s: Expected %#v, got %#v", name, expected, attributeInstance.Value)
+				}
+				if attributeInstance.Provenance.Source != "inventory" {
+					t.Errorf("Attribute %s: Expected provenance inventory, got %s", name, attributeInstance.Provenance.Source)
+				}
+			}
+			if aliases := entity.GetAliases(); len(aliases) != 1 || aliases[0] != "i-0abc" {
+				t.Errorf("Expected alias i-0abc, got %v", aliases)
+			}
+			if dependencies := entity.GetDependencies(); len(dependencies) != 1 || dependencies[0].Target.String() != "service/dns" {
+				t.Errorf("Expected dependency on service/dns, got %v", dependencies)
+			}
+		})
+	}
+}
+
+func TestDiffLargeIntegers(t *testing.T) {
+	attributeFactory := newTestAttributeFactory(t)
+	snapshot, err := NewSnapshot(newTestCollection(t, attributeFactory))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := snapshot.Encode(&b, FormatJson); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSnapshot(&b, FormatJson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Differs from the original value only beyond the precision of float64
+	for i := range snapshot.Entities[0].Attributes {
+		if snapshot.Entities[0].Attributes[i].Name == "inode" {
+			snapshot.Entities[0].Attributes[i].Value = int64(1152921504606846976)
+		}
+	}
+	diff, err := Diff(loaded, snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Entities) != 1 || len(diff.Entities[0].Attributes) != 1 || diff.Entities[0].Attributes[0].Name != "inode" {
+		t.Errorf("Expected inode to be changed, got:\n%s", diff)
+	}
+}
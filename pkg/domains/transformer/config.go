@@ -0,0 +1,134 @@
+package transformer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/discovery"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+	"go.yaml.in/yaml/v3"
+)
+
+// TransformerConfig YAML configuration of a built-in transformer.
+// Fields that are used depend on the transformer type
+type TransformerConfig struct {
+	// Type One of drop, filter_types, rename, set_attribute_from_name or copy_from_host
+	Type        string   `yaml:"type"`
+	EntityTypes []string `yaml:"entity_types"`
+	NamePattern string   `yaml:"name_pattern"`
+	// Replacement New name (rename)
+	Replacement string `yaml:"replacement"`
+	// Attribute Attribute to set (set_attribute_from_name, copy_from_host)
+	Attribute string `yaml:"attribute"`
+	// Value Value to set (set_attribute_from_name)
+	Value string `yaml:"value"`
+	// HostAttribute Attribute containing the name of the host (copy_from_host).
+	// The entity's host relationship is used if not set
+	HostAttribute string `yaml:"host_attribute"`
+	// HostType Entity type of the host (copy_from_host)
+	HostType string `yaml:"host_type"`
+	// Override Replace existing attribute values (set_attribute_from_name, copy_from_host)
+	Override bool `yaml:"override"`
+}
+
+type TransformersConfig struct {
+	Transformers []TransformerConfig `yaml:"transformers"`
+}
+
+func (c *TransformerConfig) getSelector() (EntitySelector, error) {
+	selector := EntitySelector{}
+	for _, entityType := range c.EntityTypes {
+		selector.EntityTypes = append(selector.EntityTypes, metadata.EntityType(entityType))
+	}
+	if c.NamePattern != "" {
+		pattern, err := regexp.Compile(c.NamePattern)
+		if err != nil {
+			return selector, fmt.Errorf("Invalid name pattern: %s", err)
+		}
+		selector.NamePattern = pattern
+	}
+	return selector, nil
+}
+
+func (c *TransformerConfig) getAttribute(attributeFactory *attribute.AttributeFactory) (*attribute.Attribute, error) {
+	if c.Attribute == "" {
+		return nil, fmt.Errorf("No attribute configured")
+	}
+	attr := attributeFactory.GetAttributeByName(attribute.AttributeName(c.Attribute))
+	if attr == nil {
+		return nil, fmt.Errorf("Attribute %s is not registered", c.Attribute)
+	}
+	return attr, nil
+}
+
+// NewTransformer Create a built-in transformer from configuration.
+// Attributes are resolved by name using the attribute factory
+func NewTransformer(config *TransformerConfig, attributeFactory *attribute.AttributeFactory) (discovery.Transformer, error) {
+	if config == nil {
+		return nil, fmt.Errorf("NewTransformer: config is nil")
+	}
+	if attributeFactory == nil {
+		return nil, fmt.Errorf("NewTransformer: attributeFactory is nil")
+	}
+	selector, err := config.getSelector()
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.Type {
+	case "drop":
+		return NewDropEntitiesTransformer(selector)
+	case "filter_types":
+		return NewFilterEntityTypesTransformer(selector.EntityTypes...)
+	case "rename":
+		return NewRenameEntitiesTransformer(selector.NamePattern, config.Replacement, selector.EntityTypes...)
+	case "set_attribute_from_name":
+		attr, err := config.getAttribute(attributeFactory)
+		if err != nil {
+			return nil, err
+		}
+		return NewSetAttributeFromNameTransformer(attr, selector, config.Value, config.Override)
+	case "copy_from_host":
+		attr, err := config.getAttribute(attributeFactory)
+		if err != nil {
+			return nil, err
+		}
+		return NewCopyAttributeFromHostTransformer(attr, selector, attribute.AttributeName(config.HostAttribute), metadata.EntityType(config.HostType), config.Override)
+	}
+	return nil, fmt.Errorf("NewTransformer: Unknown transformer type: %s", config.Type)
+}
+
+// LoadTransformers Create built-in transformers from YAML configuration,
+// in the order they are configured
+func LoadTransformers(r io.Reader, attributeFactory *attribute.AttributeFactory) ([]discovery.Transformer, error) {
+	var config TransformersConfig
+	decoder := yaml.NewDecoder(r)
+	// Reject misspelled keys, which would otherwise be ignored and,
+	// e.g. for a drop transformer's name_pattern, select every entity
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("LoadTransformers: Error decoding YAML: %s", err)
+	}
+	transformers := make([]discovery.Transformer, 0, len(config.Transformers))
+	for i, transformerConfig := range config.Transformers {
+		transformer, err := NewTransformer(&transformerConfig, attributeFactory)
+		if err != nil {
+			return nil, fmt.Errorf("LoadTransformers: Transformer %d (%s): %s", i+1, transformerConfig.Type, err)
+		}
+		transformers = append(transformers, transformer)
+	}
+	return transformers, nil
+}
+
+// LoadTransformersFile Create built-in transformers from a YAML file
+func LoadTransformersFile(path string, attributeFactory *attribute.AttributeFactory) ([]discovery.Transformer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadTransformers(file, attributeFactory)
+}
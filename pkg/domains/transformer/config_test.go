@@ -0,0 +1,113 @@
+package transformer
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/discovery"
+)
+
+func TestLoadTransformers(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected []string
+		err      string
+	}{
+		{
+			name: "all transformers",
+			config: `
+transformers:
+  - type: drop
+    name_pattern: "^test-"
+  - type: rename
+    entity_types: [server]
+    name_pattern: "_"
+    replacement: "-"
+  - type: set_attribute_from_name
+    name_pattern: "^(prod|staging)-"
+    attribute: environment
+    value: "$1"
+  - type: copy_from_host
+    entity_types: [service]
+    attribute: location
+  - type: filter_types
+    entity_types: [server, service]
+`,
+			expected: []string{"drop", "rename", "set_attribute_from_name", "copy_from_host", "filter_types"},
+		},
+		{
+			name:     "empty",
+			config:   "",
+			expected: []string{},
+		},
+		{
+			name: "unknown key",
+			config: `
+transformers:
+  - type: drop
+    entity_types: [server]
+    name_pattren: "^test-"
+`,
+			err: "field name_pattren not found",
+		},
+		{
+			name: "unknown type",
+			config: `
+transformers:
+  - type: uppercase
+`,
+			err: "LoadTransformers: Transformer 1 (uppercase): NewTransformer: Unknown transformer type: uppercase",
+		},
+		{
+			name: "invalid name pattern",
+			config: `
+transformers:
+  - type: drop
+    name_pattern: "("
+`,
+			err: "Invalid name pattern",
+		},
+		{
+			name: "unregistered attribute",
+			config: `
+transformers:
+  - type: copy_from_host
+    attribute: owner
+`,
+			err: "LoadTransformers: Transformer 1 (copy_from_host): Attribute owner is not registered",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attributeFactory, err := attribute.NewAttributeFactory()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, attr := range []*attribute.Attribute{&testAttributeEnvironment, &testAttributeLocation} {
+				if err := attributeFactory.RegisterAttribute(attr); err != nil {
+					t.Fatal(err)
+				}
+			}
+			transformers, err := LoadTransformers(strings.NewReader(test.config), attributeFactory)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("Expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := []string{}
+			for _, transformer := range transformers {
+				names = append(names, transformer.(discovery.NamedTransformer).GetName())
+			}
+			if !slices.Equal(names, test.expected) {
+				t.Errorf("Expected transformers %v, got %v", test.expected, names)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package transformer
+
+import (
+	"regexp"
+	"slices"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+)
+
+// TransformerSource Source name used in the provenance of values set by transformers
+const TransformerSource string = "transformer"
+
+// EntitySelector Selects the entities that a transformer applies to
+type EntitySelector struct {
+	// EntityTypes Entity types to select. All entity types are selected if empty
+	EntityTypes []metadata.EntityType
+	// NamePattern Regular expression matched against entity names.
+	// All names are selected if nil
+	NamePattern *regexp.Regexp
+}
+
+// Matches Whether the entity is selected
+func (s *EntitySelector) Matches(entity *metadata.Entity) bool {
+	if len(s.EntityTypes) > 0 && !slices.Contains(s.EntityTypes, entity.GetType()) {
+		return false
+	}
+	if s.NamePattern != nil && !s.NamePattern.MatchString(string(entity.GetName())) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,260 @@
+package transformer
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/discovery"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+)
+
+// DropEntitiesTransformer Removes selected entities, e.g. test VMs
+type DropEntitiesTransformer struct {
+	selector EntitySelector
+}
+
+func NewDropEntitiesTransformer(selector EntitySelector) (*DropEntitiesTransformer, error) {
+	if len(selector.EntityTypes) == 0 && selector.NamePattern == nil {
+		return nil, fmt.Errorf("NewDropEntitiesTransformer: Selector would drop all entities")
+	}
+	return &DropEntitiesTransformer{
+		selector: selector,
+	}, nil
+}
+
+func (t *DropEntitiesTransformer) GetName() string {
+	return "drop"
+}
+
+func (t *DropEntitiesTransformer) Transform(collection *discovery.EntityCollection) error {
+	for _, entity := range collection.GetEntities() {
+		if t.selector.Matches(entity) {
+			collection.RemoveEntity(entity.GetId())
+		}
+	}
+	return nil
+}
+
+// FilterEntityTypesTransformer Removes all entities that are not of the given types
+type FilterEntityTypesTransformer struct {
+	entityTypes []metadata.EntityType
+}
+
+func NewFilterEntityTypesTransformer(entityTypes ...metadata.EntityType) (*FilterEntityTypesTransformer, error) {
+	if len(entityTypes) == 0 {
+		return nil, fmt.Errorf("NewFilterEntityTypesTransformer: No entity types provided")
+	}
+	return &FilterEntityTypesTransformer{
+		entityTypes: entityTypes,
+	}, nil
+}
+
+func (t *FilterEntityTypesTransformer) GetName() string {
+	return "filter_types"
+}
+
+func (t *FilterEntityTypesTransformer) Transform(collection *discovery.EntityCollection) error {
+	for _, entity := range collection.GetEntities() {
+		if !slices.Contains(t.entityTypes, entity.GetType()) {
+			collection.RemoveEntity(entity.GetId())
+		}
+	}
+	return nil
+}
+
+// RenameEntitiesTransformer Renames entities by replacing matches of a
+// regular expression. The previous name is kept as an alias
+type RenameEntitiesTransformer struct {
+	entityTypes []metadata.EntityType
+	pattern     *regexp.Regexp
+	// replacement Replacement text, which may reference capture groups, e.g. "$1"
+	replacement string
+}
+
+func NewRenameEntitiesTransformer(pattern *regexp.Regexp, replacement string, entityTypes ...metadata.EntityType) (*RenameEntitiesTransformer, error) {
+	if pattern == nil {
+		return nil, fmt.Errorf("NewRenameEntitiesTransformer: pattern is nil")
+	}
+	return &RenameEntitiesTransformer{
+		entityTypes: entityTypes,
+		pattern:     pattern,
+		replacement: replacement,
+	}, nil
+}
+
+func (t *RenameEntitiesTransformer) GetName() string {
+	return "rename"
+}
+
+func (t *RenameEntitiesTransformer) Transform(collection *discovery.EntityCollection) error {
+	selector := EntitySelector{EntityTypes: t.entityTypes, NamePattern: t.pattern}
+	for _, entity := range collection.GetEntities() {
+		if !selector.Matches(entity) {
+			continue
+		}
+		name := metadata.EntityName(t.pattern.ReplaceAllString(string(entity.GetName()), t.replacement))
+		if err := collection.RenameEntity(entity.GetId(), name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setAttribute Set an attribute to a value derived by a transformer.
+// Existing values are only replaced when override is set
+func setAttribute(entity *metadata.Entity, attr *attribute.Attribute, value any, provenance attribute.Provenance, override bool) error {
+	existing := entity.GetAttributeByName(attr.Name)
+	if existing != nil && !existing.IsEmpty() && !override {
+		return nil
+	}
+	coerced, err := attr.CoerceValue(value)
+	if err != nil {
+		return fmt.Errorf("%s (%s): %s", entity.GetName(), entity.GetType(), err)
+	}
+	entity.RemoveAttribute(attr.Name)
+	if err := entity.SetAttributeWithProvenance(attr, coerced, provenance); err != nil {
+		// Restore the previous value
+		if existing != nil {
+			_ = entity.SetAttributeInstance(*existing)
+		}
+		return fmt.Errorf("%s (%s): %s", entity.GetName(), entity.GetType(), err)
+	}
+	return nil
+}
+
+// SetAttributeFromNameTransformer Sets an attribute from the entity's name,
+// e.g. environment "prod" from the name "prod-web-01".
+// The value may reference capture groups of the pattern, e.g. "$1"
+type SetAttributeFromNameTransformer struct {
+	attribute *attribute.Attribute
+	selector  EntitySelector
+	value     string
+	override  bool
+}
+
+func NewSetAttributeFromNameTransformer(attr *attribute.Attribute, selector EntitySelector, value string, override bool) (*SetAttributeFromNameTransformer, error) {
+	if attr == nil {
+		return nil, fmt.Errorf("NewSetAttributeFromNameTransformer: attribute is nil")
+	}
+	if selector.NamePattern == nil {
+		return nil, fmt.Errorf("NewSetAttributeFromNameTransformer: Selector has no name pattern")
+	}
+	return &SetAttributeFromNameTransformer{
+		attribute: attr,
+		selector:  selector,
+		value:     value,
+		override:  override,
+	}, nil
+}
+
+func (t *SetAttributeFromNameTransformer) GetName() string {
+	return "set_attribute_from_name"
+}
+
+func (t *SetAttributeFromNameTransformer) Transform(collection *discovery.EntityCollection) error {
+	for _, entity := range collection.GetEntities() {
+		if !t.selector.Matches(entity) {
+			continue
+		}
+		name := string(entity.GetName())
+		var value []byte
+		for _, submatches := range t.selector.NamePattern.FindAllStringSubmatchIndex(name, 1) {
+			value = t.selector.NamePattern.ExpandString(value, t.value, name, submatches)
+		}
+		if err := setAttribute(entity, t.attribute, string(value), attribute.Provenance{Source: TransformerSource}, t.override); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyAttributeFromHostTransformer Copies an attribute from the entity's
+// host, e.g. the location of the server that a service runs on.
+// The host is the entity's host relationship or, if a host attribute
+// is configured, the entity of hostType named by that attribute
+type CopyAttributeFromHostTransformer struct {
+	attribute     *attribute.Attribute
+	selector      EntitySelector
+	hostAttribute attribute.AttributeName
+	hostType      metadata.EntityType
+	override      bool
+}
+
+func NewCopyAttributeFromHostTransformer(attr *attribute.Attribute, selector EntitySelector, hostAttribute attribute.AttributeName, hostType metadata.EntityType, override bool) (*CopyAttributeFromHostTransformer, error) {
+	if attr == nil {
+		return nil, fmt.Errorf("NewCopyAttributeFromHostTransformer: attribute is nil")
+	}
+	if hostAttribute != "" && hostType == "" {
+		return nil, fmt.Errorf("NewCopyAttributeFromHostTransformer: Host type is required with a host attribute")
+	}
+	return &CopyAttributeFromHostTransformer{
+		attribute:     attr,
+		selector:      selector,
+		hostAttribute: hostAttribute,
+		hostType:      hostType,
+		override:      override,
+	}, nil
+}
+
+func (t *CopyAttributeFromHostTransformer) GetName() string {
+	return "copy_from_host"
+}
+
+// getHost Returns the host of the entity, or nil if the entity has no host.
+// The provenance is that of the reference to the host
+func (t *CopyAttributeFromHostTransformer) getHost(entity *metadata.Entity, collection *discovery.EntityCollection) (*metadata.Entity, metadata.EntityId, attribute.Provenance, error) {
+	if t.hostAttribute == "" {
+		hostRelationship := entity.GetHost()
+		if hostRelationship == nil {
+			return nil, metadata.EntityId{}, attribute.Provenance{}, nil
+		}
+		host, err := collection.ResolveRelationshipTarget(hostRelationship.Target)
+		return host, hostRelationship.Target, hostRelationship.Provenance, err
+	}
+
+	hostInstance := entity.GetAttributeByName(t.hostAttribute)
+	if hostInstance == nil || hostInstance.IsEmpty() {
+		return nil, metadata.EntityId{}, attribute.Provenance{}, nil
+	}
+	hostName, ok := hostInstance.Value.(string)
+	if !ok {
+		return nil, metadata.EntityId{}, hostInstance.Provenance, fmt.Errorf("Host attribute %s of %s (%s) is not a string", t.hostAttribute, entity.GetName(), entity.GetType())
+	}
+	hostId := metadata.EntityId{Name: metadata.EntityName(hostName), Type: t.hostType}
+	return collection.ResolveEntity(hostId.Name, hostId.Type), hostId, hostInstance.Provenance, nil
+}
+
+func (t *CopyAttributeFromHostTransformer) Transform(collection *discovery.EntityCollection) error {
+	for _, entity := range collection.GetEntities() {
+		if !t.selector.Matches(entity) {
+			continue
+		}
+		host, hostId, provenance, err := t.getHost(entity, collection)
+		if err != nil {
+			collection.AddWarning(fmt.Sprintf("%s (%s): %s", entity.GetName(), entity.GetType(), err), provenance)
+			continue
+		}
+		if host == nil {
+			if hostId.Name != "" {
+				collection.AddWarning(fmt.Sprintf("%s (%s): Host %s not found", entity.GetName(), entity.GetType(), hostId), provenance)
+			}
+			continue
+		}
+		value := host.GetAttributeByName(t.attribute.Name)
+		if value == nil || value.IsEmpty() {
+			continue
+		}
+		if err := setAttribute(entity, t.attribute, value.Value, value.Provenance, t.override); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var _ discovery.Transformer = &DropEntitiesTransformer{}
+var _ discovery.Transformer = &FilterEntityTypesTransformer{}
+var _ discovery.Transformer = &RenameEntitiesTransformer{}
+var _ discovery.Transformer = &SetAttributeFromNameTransformer{}
+var _ discovery.Transformer = &CopyAttributeFromHostTransformer{}
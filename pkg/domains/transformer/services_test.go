@@ -0,0 +1,288 @@
+package transformer
+
+import (
+	"reflect"
+	"regexp"
+	"slices"
+	"testing"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/discovery"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/relationship"
+)
+
+var (
+	testAttributeEnvironment = attribute.Attribute{Name: "environment", Type: reflect.TypeOf("")}
+	testAttributeLocation    = attribute.Attribute{Name: "location", Type: reflect.TypeOf("")}
+	testAttributeServer      = attribute.Attribute{Name: "server", Type: reflect.TypeOf("")}
+	testAttributeReplicas    = attribute.Attribute{Name: "replicas", Type: reflect.TypeOf(0)}
+)
+
+// testEntity Definition of an entity to add to a collection
+type testEntity struct {
+	id         string
+	hostedOn   string
+	attributes map[*attribute.Attribute]any
+}
+
+func newTestCollection(t *testing.T, definitions []testEntity) *discovery.EntityCollection {
+	t.Helper()
+	collection, err := discovery.NewEntityCollection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, definition := range definitions {
+		id := metadata.ParseEntityId(definition.id)
+		entity, err := metadata.NewEntity(id.Name, id.Type, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for attr, value := range definition.attributes {
+			if err := entity.SetAttributeWithProvenance(attr, value, attribute.Provenance{Source: "inventory"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if definition.hostedOn != "" {
+			entity.MergeRelationship(metadata.Relationship{Type: relationship.RelationshipTypeHost, Target: metadata.ParseEntityId(definition.hostedOn)})
+		}
+		if err := collection.AddEntity(entity); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return collection
+}
+
+func getEntityIds(collection *discovery.EntityCollection) []string {
+	ids := []string{}
+	for _, entity := range collection.GetEntities() {
+		ids = append(ids, entity.GetId().String())
+	}
+	return ids
+}
+
+// getAttributeValues Values of an attribute by entity, for entities
+// that have the attribute set
+func getAttributeValues(collection *discovery.EntityCollection, name attribute.AttributeName) map[string]any {
+	values := map[string]any{}
+	for _, entity := range collection.GetEntities() {
+		if attributeInstance := entity.GetAttributeByName(name); attributeInstance != nil {
+			values[entity.GetId().String()] = attributeInstance.Value
+		}
+	}
+	return values
+}
+
+func TestDropEntitiesTransformer(t *testing.T) {
+	entities := []testEntity{{id: "server/test-01"}, {id: "server/web-01"}, {id: "service/test-api"}}
+	tests := []struct {
+		name     string
+		selector EntitySelector
+		expected []string
+	}{
+		{
+			name:     "name pattern",
+			selector: EntitySelector{NamePattern: regexp.MustCompile("^test-")},
+			expected: []string{"server/web-01"},
+		},
+		{
+			name:     "name pattern and entity type",
+			selector: EntitySelector{EntityTypes: []metadata.EntityType{"server"}, NamePattern: regexp.MustCompile("^test-")},
+			expected: []string{"server/web-01", "service/test-api"},
+		},
+		{
+			name:     "entity type",
+			selector: EntitySelector{EntityTypes: []metadata.EntityType{"service"}},
+			expected: []string{"server/test-01", "server/web-01"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transformer, err := NewDropEntitiesTransformer(test.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			collection := newTestCollection(t, entities)
+			if err := transformer.Transform(collection); err != nil {
+				t.Fatal(err)
+			}
+			if ids := getEntityIds(collection); !slices.Equal(ids, test.expected) {
+				t.Errorf("Expected entities %v, got %v", test.expected, ids)
+			}
+		})
+	}
+
+	if _, err := NewDropEntitiesTransformer(EntitySelector{}); err == nil {
+		t.Error("Expected error for selector matching all entities")
+	}
+}
+
+func TestFilterEntityTypesTransformer(t *testing.T) {
+	transformer, err := NewFilterEntityTypesTransformer("server", "service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection := newTestCollection(t, []testEntity{{id: "server/web-01"}, {id: "vm/test-01"}, {id: "service/api"}})
+	if err := transformer.Transform(collection); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"server/web-01", "service/api"}
+	if ids := getEntityIds(collection); !slices.Equal(ids, expected) {
+		t.Errorf("Expected entities %v, got %v", expected, ids)
+	}
+
+	if _, err := NewFilterEntityTypesTransformer(); err == nil {
+		t.Error("Expected error without entity types")
+	}
+}
+
+func TestRenameEntitiesTransformer(t *testing.T) {
+	transformer, err := NewRenameEntitiesTransformer(regexp.MustCompile(`^(\w+)_(\d+)$`), "$1-$2", "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection := newTestCollection(t, []testEntity{{id: "server/web_01"}, {id: "server/db-01"}, {id: "service/api_01"}})
+	if err := transformer.Transform(collection); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"server/web-01", "server/db-01", "service/api_01"}
+	if ids := getEntityIds(collection); !slices.Equal(ids, expected) {
+		t.Errorf("Expected entities %v, got %v", expected, ids)
+	}
+	if entity := collection.ResolveEntity("web_01", "server"); entity == nil || entity.GetName() != "web-01" {
+		t.Errorf("Expected previous name to resolve to server/web-01, got %v", entity)
+	}
+}
+
+func TestSetAttributeFromNameTransformer(t *testing.T) {
+	entities := []testEntity{
+		{id: "server/prod-web-01"},
+		{id: "server/staging-web-01", attributes: map[*attribute.Attribute]any{&testAttributeEnvironment: "qa"}},
+		{id: "server/web-02"},
+	}
+	tests := []struct {
+		name     string
+		override bool
+		expected map[string]any
+	}{
+		{
+			name: "existing values are kept",
+			expected: map[string]any{
+				"server/prod-web-01":    "prod",
+				"server/staging-web-01": "qa",
+			},
+		},
+		{
+			name:     "override",
+			override: true,
+			expected: map[string]any{
+				"server/prod-web-01":    "prod",
+				"server/staging-web-01": "staging",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			selector := EntitySelector{NamePattern: regexp.MustCompile("^(prod|staging)-")}
+			transformer, err := NewSetAttributeFromNameTransformer(&testAttributeEnvironment, selector, "$1", test.override)
+			if err != nil {
+				t.Fatal(err)
+			}
+			collection := newTestCollection(t, entities)
+			if err := transformer.Transform(collection); err != nil {
+				t.Fatal(err)
+			}
+			if values := getAttributeValues(collection, "environment"); !reflect.DeepEqual(values, test.expected) {
+				t.Errorf("Expected values %v, got %v", test.expected, values)
+			}
+			if provenance := collection.GetEntityByNameAndType("prod-web-01", "server").GetAttributeByName("environment").Provenance; provenance.Source != TransformerSource {
+				t.Errorf("Expected provenance %s, got %s", TransformerSource, provenance.Source)
+			}
+		})
+	}
+
+	t.Run("invalid value", func(t *testing.T) {
+		transformer, err := NewSetAttributeFromNameTransformer(&testAttributeReplicas, EntitySelector{NamePattern: regexp.MustCompile("^(prod)-")}, "$1", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := transformer.Transform(newTestCollection(t, entities)); err == nil {
+			t.Error("Expected error for value that cannot be coerced")
+		}
+	})
+}
+
+func TestCopyAttributeFromHostTransformer(t *testing.T) {
+	entities := []testEntity{
+		{id: "server/web-01", attributes: map[*attribute.Attribute]any{&testAttributeLocation: "london"}},
+		{id: "service/api", hostedOn: "server/web-01"},
+		{id: "service/cache", hostedOn: "server/web-01", attributes: map[*attribute.Attribute]any{&testAttributeLocation: "paris"}},
+		{id: "service/db", attributes: map[*attribute.Attribute]any{&testAttributeServer: "web-01"}},
+		{id: "service/queue", attributes: map[*attribute.Attribute]any{&testAttributeServer: "web-02"}},
+		{id: "service/dns"},
+	}
+	tests := []struct {
+		name          string
+		hostAttribute attribute.AttributeName
+		hostType      metadata.EntityType
+		override      bool
+		expected      map[string]any
+		warnings      []string
+	}{
+		{
+			name: "host relationship",
+			expected: map[string]any{
+				"server/web-01": "london",
+				"service/api":   "london",
+				"service/cache": "paris",
+			},
+		},
+		{
+			name:     "override",
+			override: true,
+			expected: map[string]any{
+				"server/web-01": "london",
+				"service/api":   "london",
+				"service/cache": "london",
+			},
+		},
+		{
+			name:          "host attribute",
+			hostAttribute: "server",
+			hostType:      "server",
+			expected: map[string]any{
+				"server/web-01": "london",
+				"service/cache": "paris",
+				"service/db":    "london",
+			},
+			warnings: []string{"queue (service): Host server/web-02 not found"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transformer, err := NewCopyAttributeFromHostTransformer(&testAttributeLocation, EntitySelector{EntityTypes: []metadata.EntityType{"service"}}, test.hostAttribute, test.hostType, test.override)
+			if err != nil {
+				t.Fatal(err)
+			}
+			collection := newTestCollection(t, entities)
+			if err := transformer.Transform(collection); err != nil {
+				t.Fatal(err)
+			}
+			if values := getAttributeValues(collection, "location"); !reflect.DeepEqual(values, test.expected) {
+				t.Errorf("Expected values %v, got %v", test.expected, values)
+			}
+			warnings := []string{}
+			for _, diagnostic := range collection.GetDiagnostics() {
+				warnings = append(warnings, diagnostic.Message)
+			}
+			expectedWarnings := append([]string{}, test.warnings...)
+			if !slices.Equal(warnings, expectedWarnings) {
+				t.Errorf("Expected warnings %v, got %v", expectedWarnings, warnings)
+			}
+		})
+	}
+
+	if _, err := NewCopyAttributeFromHostTransformer(&testAttributeLocation, EntitySelector{}, "server", "", false); err == nil {
+		t.Error("Expected error for host attribute without host type")
+	}
+}
@@ -0,0 +1,364 @@
+package discovery
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	discoveryDomain "gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/discovery"
+	metadataDomain "gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/relationship"
+)
+
+// ExecProtocolVersion Version of the exec plugin protocol,
+// sent to the command in the request
+const ExecProtocolVersion int = 1
+
+// execMaxMessageSize Maximum size of a single message written by the command
+const execMaxMessageSize int = 10 * 1024 * 1024
+
+// execWaitDelay Time to wait for output after the command exits, for
+// processes it started outside of its process group to close its output
+const execWaitDelay time.Duration = 5 * time.Second
+
+// ExecRequest Written as JSON to the command's stdin
+type ExecRequest struct {
+	ProtocolVersion int      `json:"protocol_version"`
+	Source          string   `json:"source"`
+	EntityTypes     []string `json:"entity_types"`
+	Attributes      []string `json:"attributes"`
+	// Config Source specific configuration from ExecDiscoveryConfig.Config
+	Config any `json:"config"`
+}
+
+// ExecMessageKind Kind of message written by the command
+type ExecMessageKind string
+
+const (
+	// An entity, with its attributes
+	ExecMessageEntity ExecMessageKind = "entity"
+	// A warning or error to include in the discovery report
+	ExecMessageLog ExecMessageKind = "log"
+)
+
+// ExecMessage A single line of JSON written to the command's stdout
+type ExecMessage struct {
+	Kind ExecMessageKind `json:"kind"`
+
+	// Name Name of the entity (entity messages)
+	Name string `json:"name,omitempty"`
+	// Type Type of the entity (entity messages)
+	Type string `json:"type,omitempty"`
+	// Aliases Alternative names of the entity (entity messages)
+	Aliases []string `json:"aliases,omitempty"`
+	// Attributes Attribute values by name. Values are coerced to
+	// the type of the attribute (entity messages)
+	Attributes map[string]any `json:"attributes,omitempty"`
+	// Host Entity that the entity is hosted on, as name or type/name (entity messages)
+	Host string `json:"host,omitempty"`
+	// Dependencies Entities that the entity depends on, as name or type/name (entity messages)
+	Dependencies []string `json:"dependencies,omitempty"`
+	// Provenance Where the entity's data originated. The source
+	// is always set to the name of the exec source (entity messages)
+	Provenance attribute.Provenance `json:"provenance,omitzero"`
+
+	// Level Either "warning" or "error" (log messages). Defaults to "warning"
+	Level string `json:"level,omitempty"`
+	// Message Log message (log messages)
+	Message string `json:"message,omitempty"`
+}
+
+type ExecDiscoveryConfig struct {
+	// Name Name of the source. Defaults to the base name of the command
+	Name string
+	// Command Path to, or name of, the command to run
+	Command string
+	Args    []string
+	// Env Environment variables, in the form KEY=value,
+	// added to the environment of the current process
+	Env []string
+	// Dir Working directory of the command
+	Dir      string
+	Priority int
+	// EntityTypes Entity types that the command may provide
+	EntityTypes []metadataDomain.EntityType
+	// Attributes Attributes that the command may provide.
+	// Values provided by the command are validated against these
+	Attributes []attribute.Attribute
+	// Config Source specific configuration, sent to the command in the request
+	Config any
+}
+
+// ExecDiscovery Entity source that runs an external command.
+// The command is sent an ExecRequest on stdin and writes one
+// ExecMessage per line, as JSON, to stdout. Lines written to
+// stderr are reported as warnings
+type ExecDiscovery struct {
+	config           *ExecDiscoveryConfig
+	attributeFactory *attribute.AttributeFactory
+}
+
+func NewExecDiscovery(config *ExecDiscoveryConfig) (*ExecDiscovery, error) {
+	if config == nil {
+		return nil, fmt.Errorf("NewExecDiscovery: config is nil")
+	}
+	if config.Command == "" {
+		return nil, fmt.Errorf("NewExecDiscovery: Command is empty")
+	}
+	if len(config.EntityTypes) == 0 {
+		return nil, fmt.Errorf("NewExecDiscovery: No entity types configured")
+	}
+	if config.Name == "" {
+		config.Name = filepath.Base(config.Command)
+	}
+	attributeFactory, err := attribute.NewAttributeFactory()
+	if err != nil {
+		return nil, err
+	}
+	for _, attr := range config.Attributes {
+		if err := attributeFactory.RegisterAttribute(&attr); err != nil {
+			return nil, fmt.Errorf("NewExecDiscovery: %s", err)
+		}
+	}
+	return &ExecDiscovery{
+		config:           config,
+		attributeFactory: attributeFactory,
+	}, nil
+}
+
+func (m *ExecDiscovery) GetName() string {
+	return m.config.Name
+}
+
+func (m *ExecDiscovery) GetPriority() int {
+	return m.config.Priority
+}
+
+func (m *ExecDiscovery) GetEntityTypes() []metadataDomain.EntityType {
+	return m.config.EntityTypes
+}
+
+func (m *ExecDiscovery) GetAttributes() []attribute.Attribute {
+	return m.config.Attributes
+}
+
+func (m *ExecDiscovery) newRequest() ([]byte, error) {
+	request := ExecRequest{
+		ProtocolVersion: ExecProtocolVersion,
+		Source:          m.config.Name,
+		EntityTypes:     []string{},
+		Attributes:      []string{},
+		Config:          m.config.Config,
+	}
+	for _, entityType := range m.config.EntityTypes {
+		request.EntityTypes = append(request.EntityTypes, string(entityType))
+	}
+	for _, attr := range m.config.Attributes {
+		request.Attributes = append(request.Attributes, string(attr.Name))
+	}
+	return json.Marshal(request)
+}
+
+func (m *ExecDiscovery) GetEntitiesWithContext(ctx context.Context, collection *discoveryDomain.EntityCollection) error {
+	request, err := m.newRequest()
+	if err != nil {
+		return fmt.Errorf("Error encoding request for %s: %s", m.config.Name, err)
+	}
+
+	cmd := exec.CommandContext(ctx, m.config.Command, m.config.Args...)
+	cmd.Dir = m.config.Dir
+	cmd.Env = append(os.Environ(), m.config.Env...)
+	cmd.Stdin = bytes.NewReader(request)
+	setProcessGroup(cmd)
+	// The pipes are created here, rather than with StdoutPipe, so that the
+	// command can be waited for while its output is read
+	stdout, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer stdout.Close()
+	stderr, stderrWriter, err := os.Pipe()
+	if err != nil {
+		stdoutWriter.Close()
+		return err
+	}
+	defer stderr.Close()
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+	err = cmd.Start()
+	// The command holds its own copies of the write ends
+	stdoutWriter.Close()
+	stderrWriter.Close()
+	if err != nil {
+		return fmt.Errorf("Error starting command %s: %s", m.config.Command, err)
+	}
+
+	var waitErr error
+	exited := make(chan struct{})
+	go func() {
+		waitErr = cmd.Wait()
+		close(exited)
+	}()
+
+	// Output is read until EOF, which background processes inheriting the
+	// pipes would delay past the command exiting. Once the command exits its
+	// process group is killed, and the pipes are closed after execWaitDelay,
+	// or as soon as the context is done, to stop reading
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-exited:
+			killProcessGroup(cmd)
+			select {
+			case <-time.After(execWaitDelay):
+			case <-ctx.Done():
+			case <-done:
+				return
+			}
+		case <-done:
+			return
+		}
+		_ = stdout.Close()
+		_ = stderr.Close()
+	}()
+
+	// stderr is read concurrently, so that the command does not block
+	// writing to it, and reported once the command has finished
+	var stderrLines []string
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				stderrLines = append(stderrLines, line)
+			}
+		}
+	})
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(nil, execMaxMessageSize)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := m.processMessage(line, collection); err != nil {
+			collection.AddError(fmt.Sprintf("%s: Line %d: %s", m.config.Name, lineNumber, err), attribute.Provenance{Source: m.config.Name})
+		}
+	}
+	scanErr := scanner.Err()
+	if scanErr != nil {
+		// Drain the remaining output, so that the command can exit
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+	wg.Wait()
+	<-exited
+
+	for _, line := range stderrLines {
+		collection.AddWarning(fmt.Sprintf("%s: %s", m.config.Name, line), attribute.Provenance{Source: m.config.Name})
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if waitErr != nil {
+		var exitErr *exec.ExitError
+		if errors.As(waitErr, &exitErr) && len(stderrLines) > 0 {
+			return fmt.Errorf("Command %s failed: %s: %s", m.config.Command, waitErr, stderrLines[len(stderrLines)-1])
+		}
+		return fmt.Errorf("Command %s failed: %s", m.config.Command, waitErr)
+	}
+	if scanErr != nil {
+		return fmt.Errorf("Error reading output of command %s: %s", m.config.Command, scanErr)
+	}
+	return nil
+}
+
+// processMessage Process a single message written by the command
+func (m *ExecDiscovery) processMessage(line []byte, collection *discoveryDomain.EntityCollection) error {
+	var message ExecMessage
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&message); err != nil {
+		return fmt.Errorf("Invalid message: %s", err)
+	}
+
+	switch message.Kind {
+	case ExecMessageEntity:
+		return m.processEntityMessage(&message, collection)
+	case ExecMessageLog:
+		provenance := attribute.Provenance{Source: m.config.Name}
+		switch message.Level {
+		case "", "warning":
+			collection.AddWarning(fmt.Sprintf("%s: %s", m.config.Name, message.Message), provenance)
+		case "error":
+			collection.AddError(fmt.Sprintf("%s: %s", m.config.Name, message.Message), provenance)
+		default:
+			return fmt.Errorf("Unknown log level: %s", message.Level)
+		}
+		return nil
+	}
+	return fmt.Errorf("Unknown message kind: %s", message.Kind)
+}
+
+func (m *ExecDiscovery) processEntityMessage(message *ExecMessage, collection *discoveryDomain.EntityCollection) error {
+	if message.Name == "" {
+		return fmt.Errorf("Empty entity name")
+	}
+	entityType := metadataDomain.EntityType(message.Type)
+	if !slices.Contains(m.config.EntityTypes, entityType) {
+		return fmt.Errorf("Unknown entity type: %s", message.Type)
+	}
+
+	entity, err := metadataDomain.NewEntity(metadataDomain.EntityName(message.Name), entityType, m.GetPriority())
+	if err != nil {
+		return err
+	}
+	for _, alias := range message.Aliases {
+		entity.AddAlias(metadataDomain.EntityName(alias))
+	}
+
+	provenance := message.Provenance
+	provenance.Source = m.config.Name
+	for _, name := range slices.Sorted(maps.Keys(message.Attributes)) {
+		err := entity.SetAttributeByNameWithProvenance(m.attributeFactory, attribute.AttributeName(name), message.Attributes[name], provenance)
+		if err == nil {
+			continue
+		}
+		var validationError *attribute.ValidationError
+		if errors.As(err, &validationError) {
+			collection.AddError(fmt.Sprintf("%s (%s): %s", entity.GetName(), entity.GetType(), err), provenance)
+		} else {
+			collection.AddWarning(fmt.Sprintf("%s (%s): %s", entity.GetName(), entity.GetType(), err), provenance)
+		}
+	}
+	if message.Host != "" {
+		if err := entity.AddRelationshipWithProvenance(relationship.RelationshipTypeHost, metadataDomain.ParseEntityId(message.Host), provenance); err != nil {
+			collection.AddWarning(err.Error(), provenance)
+		}
+	}
+	for _, dependency := range message.Dependencies {
+		if err := entity.AddRelationshipWithProvenance(relationship.RelationshipTypeNormal, metadataDomain.ParseEntityId(dependency), provenance); err != nil {
+			collection.AddWarning(err.Error(), provenance)
+		}
+	}
+	return collection.AddEntity(entity)
+}
+
+var _ discoveryDomain.ContextEntitySource = &ExecDiscovery{}
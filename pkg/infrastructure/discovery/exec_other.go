@@ -0,0 +1,12 @@
+//go:build !unix
+
+package discovery
+
+import "os/exec"
+
+// setProcessGroup Process groups are not supported on this platform,
+// so only the command itself is killed on cancellation
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup Process groups are not supported on this platform
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,294 @@
+//go:build unix
+
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
+	discoveryDomain "gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/discovery"
+	metadataDomain "gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+)
+
+var testExecAttributePort = attribute.Attribute{Name: "port", Type: reflect.TypeOf(0)}
+
+// newTestExecDiscovery Exec source running a shell script
+func newTestExecDiscovery(t *testing.T, script string) *ExecDiscovery {
+	t.Helper()
+	source, err := NewExecDiscovery(&ExecDiscoveryConfig{
+		Name:        "inventory",
+		Command:     "/bin/sh",
+		Args:        []string{"-c", script},
+		EntityTypes: []metadataDomain.EntityType{"server", "service"},
+		Attributes:  []attribute.Attribute{testExecAttributePort},
+		Config:      map[string]string{"region": "eu-west-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return source
+}
+
+func getDiagnosticMessages(collection *discoveryDomain.EntityCollection, severity discoveryDomain.DiagnosticSeverity) []string {
+	messages := []string{}
+	for _, diagnostic := range collection.GetDiagnostics() {
+		if diagnostic.Severity == severity {
+			messages = append(messages, diagnostic.Message)
+		}
+	}
+	return messages
+}
+
+func TestExecDiscoveryMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   []string
+		entities []string
+		errors   []string
+		warnings []string
+	}{
+		{
+			name: "entities",
+			output: []string{
+				`{"kind": "entity", "name": "web-01", "type": "server"}`,
+				``,
+				`{"kind": "entity", "name": "api", "type": "service", "attributes": {"port": "8080"}, "host": "server/web-01", "dependencies": ["db"]}`,
+			},
+			entities: []string{"server/web-01", "service/api"},
+		},
+		{
+			name: "log messages",
+			output: []string{
+				`{"kind": "log", "message": "Slow response"}`,
+				`{"kind": "log", "level": "error", "message": "Region unavailable"}`,
+				`{"kind": "log", "level": "debug", "message": "Ignored"}`,
+			},
+			entities: []string{},
+			errors:   []string{"inventory: Region unavailable", "inventory: Line 3: Unknown log level: debug"},
+			warnings: []string{"inventory: Slow response"},
+		},
+		{
+			name: "invalid messages",
+			output: []string{
+				`not json`,
+				`{"kind": "metric", "name": "web-01"}`,
+				`{"kind": "entity", "name": "web-01", "type": "server", "colour": "blue"}`,
+				`{"kind": "entity", "name": "web-01", "type": "database"}`,
+				`{"kind": "entity", "type": "server"}`,
+				`{"kind": "entity", "name": "web-02", "type": "server"}`,
+			},
+			entities: []string{"server/web-02"},
+			errors: []string{
+				"inventory: Line 1: Invalid message: invalid character 'o' in literal null (expecting 'u')",
+				"inventory: Line 2: Unknown message kind: metric",
+				`inventory: Line 3: Invalid message: json: unknown field "colour"`,
+				"inventory: Line 4: Unknown entity type: database",
+				"inventory: Line 5: Empty entity name",
+			},
+		},
+		{
+			name: "invalid attribute values",
+			output: []string{
+				`{"kind": "entity", "name": "api", "type": "service", "attributes": {"port": "http", "owner": "team"}}`,
+			},
+			entities: []string{"service/api"},
+			warnings: []string{
+				"api (service): CoerceValue: Attribute owner is not registered",
+				"api (service): Cannot coerce value for attribute port: Cannot coerce 'http' (string) to int: Not an integer",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			directory := t.TempDir()
+			outputPath := filepath.Join(directory, "output")
+			if err := os.WriteFile(outputPath, []byte(strings.Join(test.output, "\n")), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			source := newTestExecDiscovery(t, "cat > /dev/null; cat "+outputPath)
+			collection, err := discoveryDomain.NewEntityCollection()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := source.GetEntitiesWithContext(context.Background(), collection); err != nil {
+				t.Fatal(err)
+			}
+
+			entities := []string{}
+			for _, entity := range collection.GetEntities() {
+				entities = append(entities, entity.GetId().String())
+			}
+			if !slices.Equal(entities, test.entities) {
+				t.Errorf("Expected entities %v, got %v", test.entities, entities)
+			}
+			expectedErrors := append([]string{}, test.errors...)
+			if errors := getDiagnosticMessages(collection, discoveryDomain.DiagnosticSeverityError); !slices.Equal(errors, expectedErrors) {
+				t.Errorf("Expected errors %q, got %q", expectedErrors, errors)
+			}
+			expectedWarnings := append([]string{}, test.warnings...)
+			if warnings := getDiagnosticMessages(collection, discoveryDomain.DiagnosticSeverityWarning); !slices.Equal(warnings, expectedWarnings) {
+				t.Errorf("Expected warnings %q, got %q", expectedWarnings, warnings)
+			}
+		})
+	}
+}
+
+func TestExecDiscoveryEntity(t *testing.T) {
+	source := newTestExecDiscovery(t, `cat > /dev/null; echo '{"kind": "entity", "name": "api", "type": "service", "aliases": ["api-01"], "attributes": {"port": 8080}, "host": "server/web-01", "dependencies": ["db"]}'`)
+	collection, err := discoveryDomain.NewEntityCollection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := source.GetEntitiesWithContext(context.Background(), collection); err != nil {
+		t.Fatal(err)
+	}
+	entity := collection.GetEntityByNameAndType("api", "service")
+	if entity == nil {
+		t.Fatal("Expected entity service/api")
+	}
+	if !slices.Contains(entity.GetNames(), "api-01") {
+		t.Errorf("Expected alias api-01, got %v", entity.GetNames())
+	}
+	port := entity.GetAttributeByName("port")
+	if port == nil || port.Value != 8080 || port.Provenance.Source != "inventory" {
+		t.Errorf("Expected port 8080 from inventory, got %#v", port)
+	}
+	if host := entity.GetHost(); host == nil || host.Target.String() != "server/web-01" {
+		t.Errorf("Expected host server/web-01, got %v", host)
+	}
+	if dependencies := entity.GetDependencies(); len(dependencies) != 1 || dependencies[0].Target.String() != "db" {
+		t.Errorf("Expected dependency on db, got %v", dependencies)
+	}
+}
+
+func TestExecDiscoveryRequest(t *testing.T) {
+	requestPath := filepath.Join(t.TempDir(), "request")
+	source := newTestExecDiscovery(t, "cat > "+requestPath)
+	collection, err := discoveryDomain.NewEntityCollection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := source.GetEntitiesWithContext(context.Background(), collection); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(requestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var request ExecRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		t.Fatal(err)
+	}
+	expected := ExecRequest{
+		ProtocolVersion: ExecProtocolVersion,
+		Source:          "inventory",
+		EntityTypes:     []string{"server", "service"},
+		Attributes:      []string{"port"},
+		Config:          map[string]any{"region": "eu-west-1"},
+	}
+	if !reflect.DeepEqual(request, expected) {
+		t.Errorf("Expected request %#v, got %#v", expected, request)
+	}
+}
+
+func TestExecDiscoveryExit(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		err      string
+		warnings []string
+	}{
+		{
+			name:     "stderr is reported as warnings",
+			script:   "echo 'Using cached inventory' >&2; echo >&2; echo 'Cache is 2 days old' >&2",
+			warnings: []string{"inventory: Using cached inventory", "inventory: Cache is 2 days old"},
+		},
+		{
+			name:     "non-zero exit",
+			script:   "echo 'Authentication failed' >&2; exit 3",
+			err:      "Command /bin/sh failed: exit status 3: Authentication failed",
+			warnings: []string{"inventory: Authentication failed"},
+		},
+		{
+			name:   "non-zero exit without stderr",
+			script: "exit 1",
+			err:    "Command /bin/sh failed: exit status 1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source := newTestExecDiscovery(t, test.script)
+			collection, err := discoveryDomain.NewEntityCollection()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = source.GetEntitiesWithContext(context.Background(), collection)
+			if test.err == "" && err != nil {
+				t.Errorf("Expected no error, got %s", err)
+			}
+			if test.err != "" && (err == nil || err.Error() != test.err) {
+				t.Errorf("Expected error %q, got %v", test.err, err)
+			}
+			expectedWarnings := append([]string{}, test.warnings...)
+			if warnings := getDiagnosticMessages(collection, discoveryDomain.DiagnosticSeverityWarning); !slices.Equal(warnings, expectedWarnings) {
+				t.Errorf("Expected warnings %q, got %q", expectedWarnings, warnings)
+			}
+		})
+	}
+}
+
+func TestExecDiscoveryCancellation(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{name: "command", script: "sleep 30"},
+		{name: "background process holding output", script: "sleep 30 & wait"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source := newTestExecDiscovery(t, test.script)
+			collection, err := discoveryDomain.NewEntityCollection()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			err = source.GetEntitiesWithContext(ctx, collection)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("Expected command to be stopped at the deadline, took %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestExecDiscoveryBackgroundProcess(t *testing.T) {
+	source := newTestExecDiscovery(t, `(sleep 30 &); echo '{"kind": "entity", "name": "web-01", "type": "server"}'`)
+	collection, err := discoveryDomain.NewEntityCollection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := source.GetEntitiesWithContext(context.Background(), collection); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected to return once the command exits, took %s", elapsed)
+	}
+	if collection.Len() != 1 {
+		t.Errorf("Expected 1 entity, got %d", collection.Len())
+	}
+}
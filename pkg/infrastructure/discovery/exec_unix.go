@@ -0,0 +1,23 @@
+//go:build unix
+
+package discovery
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup Start the command in its own process group, so that
+// processes it starts in the background are killed with it
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// killProcessGroup Kill processes remaining in the command's process
+// group, once the command has exited
+func killProcessGroup(cmd *exec.Cmd) {
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
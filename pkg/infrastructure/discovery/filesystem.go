@@ -2,36 +2,50 @@ package discovery
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io/fs"
 	"maps"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/attribute"
 	commontypes "gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/common_types"
 	discoveryDomain "gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/discovery"
 	metadataDomain "gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/relationship"
 	"go.yaml.in/yaml/v3"
 )
 
-// type StorageMetadata struct {
-// }
+type StorageMetadata struct {
+	Volume                   string `yaml:"volume"`
+	ParticipatesInHostBackup bool   `yaml:"participates_in_host_backup"`
+}
 
 type FilesystemEntityMetadata struct {
-	Type      metadataDomain.EntityType `yaml:"type"`
-	Name      string                    `yaml:"name"`
-	IpAddress string                    `yaml:"ip_address"`
-	Url       string                    `yaml:"url"`
-	// Criticality  string                    `yaml:"criticality"`
-	// Host         string                    `yaml:"host"`
-	// Storage      StorageMetadata           `yaml:"storage"`
-	// Dependencies []string                  `yaml:"dependencies"`
+	Type        metadataDomain.EntityType `yaml:"type"`
+	Name        string                    `yaml:"name"`
+	Aliases     []string                  `yaml:"aliases"`
+	IpAddress   string                    `yaml:"ip_address"`
+	Url         string                    `yaml:"url"`
+	Criticality string                    `yaml:"criticality"`
+	Lifecycle   string                    `yaml:"lifecycle"`
+	Storage     *StorageMetadata          `yaml:"storage"`
+	Rto         time.Duration             `yaml:"rto"`
+	Ports       map[string]int            `yaml:"ports"`
+	// Host Name of the entity that this entity is hosted on, as name or type/name
+	Host string `yaml:"host"`
+	// Dependencies Names of entities that this entity depends on, as name or type/name
+	Dependencies []string `yaml:"dependencies"`
 	// Terraform    []string                  `yaml:"terraform"`
 }
 
 type FilesystemDiscoveryConfig struct {
+	// Name Name of the source. Defaults to "filesystem". Must be
+	// set to distinguish multiple filesystem sources
+	Name                   string
 	BaseDirectory          string
 	DirectoryToTypeMapping map[string]string
 	FileExtensions         []string
@@ -52,6 +66,11 @@ func (m *FilesystemDiscovery) GetAttributes() []attribute.Attribute {
 	return []attribute.Attribute{
 		commontypes.AttributeIpAddress,
 		commontypes.AttributeUrl,
+		commontypes.AttributeCriticality,
+		commontypes.AttributeStorage,
+		commontypes.AttributeRecoveryTimeObjective,
+		commontypes.AttributePorts,
+		metadataDomain.AttributeLifecycle,
 	}
 }
 
@@ -95,6 +114,9 @@ func NewFilesystemDiscovery(config *FilesystemDiscoveryConfig) (*FilesystemDisco
 		}
 		return nil, fmt.Errorf("NewFilesystemDiscovery: BaseDirectory does not exist or is not a directory")
 	}
+	if config.Name == "" {
+		config.Name = "filesystem"
+	}
 
 	return &FilesystemDiscovery{
 		config: config,
@@ -112,7 +134,55 @@ func (m *FilesystemDiscovery) convertFilepathToType(filePath string) metadataDom
 	return ""
 }
 
-func (m *FilesystemDiscovery) processRawFilesystemMetadata(raw *FilesystemEntityMetadata, collection *discoveryDomain.EntityCollection, filePath string) error {
+// getYamlKeyLine Returns the line number of a key within a YAML document
+func getYamlKeyLine(node *yaml.Node, key string) int {
+	if node == nil {
+		return 0
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return node.Line
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i].Line
+		}
+	}
+	return node.Line
+}
+
+func (m *FilesystemDiscovery) getProvenance(filePath string, node *yaml.Node, key string) attribute.Provenance {
+	return attribute.Provenance{
+		Source: m.GetName(),
+		File:   filePath,
+		Line:   getYamlKeyLine(node, key),
+	}
+}
+
+// setAttribute Set attribute on entity, with the provenance of the
+// attribute's key in the document. Values that cannot be set are
+// recorded as warnings, or errors if the value failed validation
+func (m *FilesystemDiscovery) setAttribute(entity *metadataDomain.Entity, collection *discoveryDomain.EntityCollection, attr *attribute.Attribute, value any, filePath string, node *yaml.Node) {
+	provenance := m.getProvenance(filePath, node, string(attr.Name))
+	if err := entity.SetAttributeWithProvenance(attr, value, provenance); err != nil {
+		var validationError *attribute.ValidationError
+		if errors.As(err, &validationError) {
+			collection.AddError(fmt.Sprintf("%s (%s): %s", entity.GetName(), entity.GetType(), err), provenance)
+		} else {
+			collection.AddWarning(err.Error(), provenance)
+		}
+	}
+}
+
+func (m *FilesystemDiscovery) addRelationship(entity *metadataDomain.Entity, collection *discoveryDomain.EntityCollection, relationshipType relationship.RelationshipType, reference string, provenance attribute.Provenance) {
+	if err := entity.AddRelationshipWithProvenance(relationshipType, metadataDomain.ParseEntityId(reference), provenance); err != nil {
+		collection.AddWarning(err.Error(), provenance)
+	}
+}
+
+func (m *FilesystemDiscovery) processRawFilesystemMetadata(raw *FilesystemEntityMetadata, node *yaml.Node, collection *discoveryDomain.EntityCollection, filePath string) error {
 	// Attempt to extract type from path
 	if raw.Type == "" {
 		if pathType := m.convertFilepathToType(filePath); pathType != "" {
@@ -131,19 +201,47 @@ func (m *FilesystemDiscovery) processRawFilesystemMetadata(raw *FilesystemEntity
 	if err != nil {
 		return err
 	}
+	for _, alias := range raw.Aliases {
+		entity.AddAlias(metadataDomain.EntityName(alias))
+	}
 
 	fmt.Printf("%s\n", raw.Type)
 	switch entity.Type {
 	case commontypes.EntityServer:
 		fmt.Printf("Processing Server entity\n")
-		entity.SetAttribute(&commontypes.AttributeIpAddress, raw.IpAddress)
+		m.setAttribute(entity, collection, &commontypes.AttributeIpAddress, raw.IpAddress, filePath, node)
 
 	case commontypes.EntityService:
 		fmt.Printf("Processing Service entity\n")
-		entity.SetAttribute(&commontypes.AttributeUrl, raw.Url)
+		m.setAttribute(entity, collection, &commontypes.AttributeUrl, raw.Url, filePath, node)
 
 	default:
-		return fmt.Errorf("Unknown entity type: %s\n", raw.Type)
+		return fmt.Errorf("Unknown entity type: %s", raw.Type)
+	}
+
+	if raw.Criticality != "" {
+		m.setAttribute(entity, collection, &commontypes.AttributeCriticality, raw.Criticality, filePath, node)
+	}
+	if raw.Storage != nil {
+		m.setAttribute(entity, collection, &commontypes.AttributeStorage, map[string]any{
+			"volume":                      raw.Storage.Volume,
+			"participates_in_host_backup": raw.Storage.ParticipatesInHostBackup,
+		}, filePath, node)
+	}
+	if raw.Rto != 0 {
+		m.setAttribute(entity, collection, &commontypes.AttributeRecoveryTimeObjective, raw.Rto, filePath, node)
+	}
+	if len(raw.Ports) > 0 {
+		m.setAttribute(entity, collection, &commontypes.AttributePorts, raw.Ports, filePath, node)
+	}
+	if raw.Lifecycle != "" {
+		m.setAttribute(entity, collection, &metadataDomain.AttributeLifecycle, raw.Lifecycle, filePath, node)
+	}
+	if raw.Host != "" {
+		m.addRelationship(entity, collection, relationship.RelationshipTypeHost, raw.Host, m.getProvenance(filePath, node, "host"))
+	}
+	for _, dependency := range raw.Dependencies {
+		m.addRelationship(entity, collection, relationship.RelationshipTypeNormal, dependency, m.getProvenance(filePath, node, "dependencies"))
 	}
 	fmt.Printf("Entity: %#v\n", entity)
 	if entity != nil {
@@ -166,20 +264,27 @@ func (m *FilesystemDiscovery) processFile(collection *discoveryDomain.EntityColl
 	decoder := yaml.NewDecoder(bytes.NewReader(fileData))
 
 	for {
-		var raw FilesystemEntityMetadata
-		err := decoder.Decode(&raw)
+		var node yaml.Node
+		err := decoder.Decode(&node)
 		if err != nil {
 			if err.Error() == "EOF" {
 				break
 			}
-			fmt.Println(err)
+			// The decoder cannot recover from invalid YAML,
+			// so the remainder of the file is skipped
+			collection.AddError(fmt.Sprintf("Error parsing YAML: %s", err), attribute.Provenance{File: filePath})
+			break
+		}
+		var raw FilesystemEntityMetadata
+		if err := node.Decode(&raw); err != nil {
+			collection.AddError(fmt.Sprintf("Error decoding document: %s", err), m.getProvenance(filePath, &node, ""))
 			continue
 		}
 		fmt.Printf("--- Document ---\n")
 		fmt.Printf("%#v\n", raw)
-		err = m.processRawFilesystemMetadata(&raw, collection, filePath)
+		err = m.processRawFilesystemMetadata(&raw, &node, collection, filePath)
 		if err != nil {
-			fmt.Printf("processFile: Error processing file fragment: %s\n", err)
+			collection.AddError(fmt.Sprintf("Error processing document: %s", err), m.getProvenance(filePath, &node, ""))
 		}
 	}
 	return nil
@@ -192,12 +297,19 @@ func (m *FilesystemDiscovery) GetEntities(collection *discoveryDomain.EntityColl
 	}
 
 	for _, filePath := range filePaths {
-		m.processFile(collection, filePath)
+		if err := m.processFile(collection, filePath); err != nil {
+			collection.AddError(fmt.Sprintf("Error processing file: %s", err), attribute.Provenance{File: filePath})
+		}
 	}
 	return nil
 }
+func (m *FilesystemDiscovery) GetName() string {
+	return m.config.Name
+}
+
 func (m *FilesystemDiscovery) GetPriority() int {
 	return 50
 }
 
 var _ discoveryDomain.EntitySource = &FilesystemDiscovery{}
+var _ discoveryDomain.NamedEntitySource = &FilesystemDiscovery{}
@@ -1,7 +1,9 @@
 package documentstorage
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path"
 
@@ -9,6 +11,11 @@ import (
 	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/metadata"
 )
 
+// ArchiveDirectory Directory, within the output directory,
+// that documents of decommissioned entities are archived to.
+// Hidden, so that it cannot collide with the directory of an entity type
+const ArchiveDirectory string = ".archive"
+
 type DocumentStorageFileConfig struct {
 	OutputDirectory string
 }
@@ -40,4 +47,28 @@ func (d DocumentStorageFile) StoreDocument(entityName metadata.EntityName, entit
 	return os.WriteFile(path.Join(typeDir, fmt.Sprintf("%s.md", string(entityName))), document, 0o644)
 }
 
+// ArchiveDocument Store the document under the archive directory,
+// removing any document previously stored for the entity
+func (d DocumentStorageFile) ArchiveDocument(entityName metadata.EntityName, entityType metadata.EntityType, document []byte) error {
+	archiveDir := path.Join(d.config.OutputDirectory, ArchiveDirectory, string(entityType))
+	if err := os.MkdirAll(archiveDir, 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path.Join(archiveDir, fmt.Sprintf("%s.md", string(entityName))), document, 0o644); err != nil {
+		return err
+	}
+	return d.RemoveDocument(entityName, entityType)
+}
+
+// RemoveDocument Remove the document previously stored for the entity, if any
+func (d DocumentStorageFile) RemoveDocument(entityName metadata.EntityName, entityType metadata.EntityType) error {
+	err := os.Remove(path.Join(d.config.OutputDirectory, string(entityType), fmt.Sprintf("%s.md", string(entityName))))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
 var _ documentgenerator.DocumentStorage = DocumentStorageFile{}
+var _ documentgenerator.DocumentArchiver = DocumentStorageFile{}
+var _ documentgenerator.DocumentRemover = DocumentStorageFile{}
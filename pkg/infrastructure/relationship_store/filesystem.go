@@ -0,0 +1,188 @@
+package relationshipstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/relationship"
+)
+
+// relationshipStoreFileVersion Version of the file format written by RelationshipStoreFile
+const relationshipStoreFileVersion int = 1
+
+type relationshipStoreFileContent struct {
+	Version  int                   `json:"version"`
+	Entities []relationship.Entity `json:"entities"`
+}
+
+type RelationshipStoreFileConfig struct {
+	// Path Path to the JSON file used to store relationships.
+	// The file is created if it does not exist
+	Path string
+}
+
+// RelationshipStoreFile Thread-safe relationship store persisted to a JSON file.
+// The file is read when the store is created and rewritten after each change.
+// Writes are atomic, so an interrupted run does not corrupt the file
+type RelationshipStoreFile struct {
+	config   *RelationshipStoreFileConfig
+	mu       sync.RWMutex
+	entities map[string]relationship.Entity
+}
+
+func NewRelationshipStoreFile(config *RelationshipStoreFileConfig) (*RelationshipStoreFile, error) {
+	if config == nil {
+		return nil, fmt.Errorf("NewRelationshipStoreFile: config is nil")
+	}
+	if config.Path == "" {
+		return nil, fmt.Errorf("NewRelationshipStoreFile: Path is empty")
+	}
+	store := &RelationshipStoreFile{
+		config:   config,
+		entities: map[string]relationship.Entity{},
+	}
+	if err := store.load(); err != nil {
+		return nil, fmt.Errorf("NewRelationshipStoreFile: %s", err)
+	}
+	return store, nil
+}
+
+// load Read entities from the file, if it exists
+func (r *RelationshipStoreFile) load() error {
+	data, err := os.ReadFile(r.config.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var content relationshipStoreFileContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return fmt.Errorf("Error decoding %s: %s", r.config.Path, err)
+	}
+	if content.Version != relationshipStoreFileVersion {
+		return fmt.Errorf("Unsupported version %d in %s", content.Version, r.config.Path)
+	}
+	for _, entity := range content.Entities {
+		r.entities[entity.Name] = entity.Clone()
+	}
+	return nil
+}
+
+// save Write all entities to the file, replacing it atomically.
+// Must be called with the lock held
+func (r *RelationshipStoreFile) save() error {
+	content := relationshipStoreFileContent{
+		Version:  relationshipStoreFileVersion,
+		Entities: make([]relationship.Entity, 0, len(r.entities)),
+	}
+	for _, name := range slices.Sorted(maps.Keys(r.entities)) {
+		content.Entities = append(content.Entities, r.entities[name])
+	}
+	data, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	directory := filepath.Dir(r.config.Path)
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(directory, fmt.Sprintf(".%s.*", filepath.Base(r.config.Path)))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), r.config.Path)
+}
+
+func (r *RelationshipStoreFile) GetEntityByName(name string) (*relationship.Entity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entity, ok := r.entities[name]
+	if !ok {
+		return nil, nil
+	}
+	clone := entity.Clone()
+	return &clone, nil
+}
+
+// GetEntities Returns copies of all entities, sorted by name
+func (r *RelationshipStoreFile) GetEntities() ([]relationship.Entity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entities := make([]relationship.Entity, 0, len(r.entities))
+	for _, name := range slices.Sorted(maps.Keys(r.entities)) {
+		entity := r.entities[name]
+		entities = append(entities, entity.Clone())
+	}
+	return entities, nil
+}
+
+func (r *RelationshipStoreFile) UpsertEntity(entity relationship.Entity) error {
+	if entity.Name == "" {
+		return fmt.Errorf("UpsertEntity: Cannot store entity with empty name")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous, existed := r.entities[entity.Name]
+	r.entities[entity.Name] = entity.Clone()
+	if err := r.save(); err != nil {
+		// Keep the store consistent with the file
+		if existed {
+			r.entities[entity.Name] = previous
+		} else {
+			delete(r.entities, entity.Name)
+		}
+		return fmt.Errorf("UpsertEntity: Error saving %s: %s", r.config.Path, err)
+	}
+	return nil
+}
+
+func (r *RelationshipStoreFile) UpdateEntities(names []string, update func(entities map[string]*relationship.Entity) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entities, err := updateEntities(r.entities, names, update)
+	if err != nil {
+		return err
+	}
+	previous := map[string]relationship.Entity{}
+	for name := range entities {
+		if entity, ok := r.entities[name]; ok {
+			previous[name] = entity
+		}
+	}
+	maps.Copy(r.entities, entities)
+	if err := r.save(); err != nil {
+		// Keep the store consistent with the file
+		for name := range entities {
+			if entity, ok := previous[name]; ok {
+				r.entities[name] = entity
+			} else {
+				delete(r.entities, name)
+			}
+		}
+		return fmt.Errorf("UpdateEntities: Error saving %s: %s", r.config.Path, err)
+	}
+	return nil
+}
+
+var _ relationship.RelationshipStore = &RelationshipStoreFile{}
@@ -0,0 +1,70 @@
+package relationshipstore
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/relationship"
+)
+
+// RelationshipStoreMemory Thread-safe relationship store held in memory.
+// Entities are copied on read and write, with relationship targets
+// stored by name only
+type RelationshipStoreMemory struct {
+	mu       sync.RWMutex
+	entities map[string]relationship.Entity
+}
+
+func NewRelationshipStoreMemory() (*RelationshipStoreMemory, error) {
+	return &RelationshipStoreMemory{
+		entities: map[string]relationship.Entity{},
+	}, nil
+}
+
+func (r *RelationshipStoreMemory) GetEntityByName(name string) (*relationship.Entity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entity, ok := r.entities[name]
+	if !ok {
+		return nil, nil
+	}
+	clone := entity.Clone()
+	return &clone, nil
+}
+
+// GetEntities Returns copies of all entities, sorted by name
+func (r *RelationshipStoreMemory) GetEntities() ([]relationship.Entity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entities := make([]relationship.Entity, 0, len(r.entities))
+	for _, name := range slices.Sorted(maps.Keys(r.entities)) {
+		entity := r.entities[name]
+		entities = append(entities, entity.Clone())
+	}
+	return entities, nil
+}
+
+func (r *RelationshipStoreMemory) UpsertEntity(entity relationship.Entity) error {
+	if entity.Name == "" {
+		return fmt.Errorf("UpsertEntity: Cannot store entity with empty name")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entities[entity.Name] = entity.Clone()
+	return nil
+}
+
+func (r *RelationshipStoreMemory) UpdateEntities(names []string, update func(entities map[string]*relationship.Entity) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entities, err := updateEntities(r.entities, names, update)
+	if err != nil {
+		return err
+	}
+	maps.Copy(r.entities, entities)
+	return nil
+}
+
+var _ relationship.RelationshipStore = &RelationshipStoreMemory{}
@@ -0,0 +1,39 @@
+package relationshipstore
+
+import (
+	"fmt"
+
+	"gitlab.dockstudios.co.uk/dockstudios/dr-docer/pkg/domains/relationship"
+)
+
+// updateEntities Call update with copies of the named entities and return
+// copies of the entities to store. Must be called with the store's lock held
+func updateEntities(stored map[string]relationship.Entity, names []string, update func(entities map[string]*relationship.Entity) error) (map[string]relationship.Entity, error) {
+	entities := map[string]*relationship.Entity{}
+	for _, name := range names {
+		entity, ok := stored[name]
+		if !ok {
+			entities[name] = nil
+			continue
+		}
+		clone := entity.Clone()
+		entities[name] = &clone
+	}
+	if err := update(entities); err != nil {
+		return nil, err
+	}
+	updated := map[string]relationship.Entity{}
+	for name, entity := range entities {
+		if entity == nil {
+			continue
+		}
+		if name == "" {
+			return nil, fmt.Errorf("UpdateEntities: Cannot store entity with empty name")
+		}
+		if entity.Name != name {
+			return nil, fmt.Errorf("UpdateEntities: Entity %s stored under name %s", entity.Name, name)
+		}
+		updated[name] = entity.Clone()
+	}
+	return updated, nil
+}